@@ -25,45 +25,386 @@
 // The parser even adds any file in attributes like *os.File or io.Reader, io.Writer, etc.
 //
 // Also the parser can even parse a database connection onto any *sql.DB using driver/connectionstring as value where if the driver is not specified
-// it will use 'postgres' as default.
+// it will use 'postgres' as default. The default driver can be changed using SetDefaultDriver, an explicit driver always takes precedence.
 //
 package strct
 
 import (
+	"context"
 	"database/sql"
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"net"
+	"net/url"
 	"os"
 	"reflect"
-	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 )
 
-// ErrNoPtr gets thrown if the inserted object is not a pointer or a struct type
+// ErrNoPtr gets thrown if the inserted object is not a pointer or a struct type, it's matched by errors.Is
+// for each of ErrNotPtr, ErrNilPtr and ErrNotStruct
 var ErrNoPtr = fmt.Errorf(`insert is not a pointer or a struct`)
 
-// Scan scans the properties of the given object struct
+// ErrNotPtr gets thrown if the inserted object is not a pointer
+var ErrNotPtr = fmt.Errorf(`%w: not a pointer`, ErrNoPtr)
+
+// ErrNilPtr gets thrown if the inserted object is a nil pointer
+var ErrNilPtr = fmt.Errorf(`%w: nil pointer`, ErrNoPtr)
+
+// ErrNotStruct gets thrown if the inserted object doesn't point to a struct
+var ErrNotStruct = fmt.Errorf(`%w: not a struct`, ErrNoPtr)
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	flagValueType       = reflect.TypeOf((*flag.Value)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+	byteType            = reflect.TypeOf(byte(0))
+	ipType              = reflect.TypeOf(net.IP{})
+	bigIntType          = reflect.TypeOf(new(big.Int))
+	fileModeType        = reflect.TypeOf(os.FileMode(0))
+	bigFloatType        = reflect.TypeOf(new(big.Float))
+	emptyInterfaceType  = reflect.TypeOf((*interface{})(nil)).Elem()
+	tcpAddrType         = reflect.TypeOf(new(net.TCPAddr))
+	urlType             = reflect.TypeOf(new(url.URL))
+)
+
+// nullTypes are the sql null types of which the first field holds the value and Valid reports if it's set
+var nullTypes = map[reflect.Type]bool{
+	reflect.TypeOf(sql.NullString{}):  true,
+	reflect.TypeOf(sql.NullInt64{}):   true,
+	reflect.TypeOf(sql.NullInt32{}):   true,
+	reflect.TypeOf(sql.NullFloat64{}): true,
+	reflect.TypeOf(sql.NullBool{}):    true,
+	reflect.TypeOf(sql.NullTime{}):    true,
+}
+
+// valueTypes are the struct types besides the sql null types which the parser sets as a single value,
+// the scanner doesn't scan their fields as nested properties
+var valueTypes = map[reflect.Type]bool{
+	timeType:            true,
+	urlType.Elem():      true,
+	tcpAddrType.Elem():  true,
+	bigIntType.Elem():   true,
+	bigFloatType.Elem(): true,
+}
+
+// isValueType reports if the parser sets the struct type as a single value
+func isValueType(t reflect.Type) bool {
+	return valueTypes[t] || nullTypes[t]
+}
+
+// ErrUnsupportedType gets thrown if the parser doesn't know how to set a pointer or interface type
+var ErrUnsupportedType = fmt.Errorf(`unsupported type`)
+
+// ErrUnexported gets thrown in strict scans if an unexported field has one of the strict tags
+var ErrUnexported = fmt.Errorf(`unexported field`)
+
+// ErrRequired gets thrown if a required field doesn't receive a value
+var ErrRequired = fmt.Errorf(`missing required value`)
+
+// ErrNotAllowed gets thrown if the value of a field isn't one of the values allowed by its oneof tag
+var ErrNotAllowed = fmt.Errorf(`value not allowed`)
+
+// Scan scans the properties of the given object struct, fields with the `strct:"-"` tag are skipped as well as
+// channel and func fields
 func Scan(obj interface{}, onProperty func(reflect.StructField, *reflect.Value) error) error {
 	return ScanAll(obj, func(f reflect.StructField) error { return nil }, onProperty)
 }
 
-// ScanAll scans each structs attribute
-func ScanAll(obj interface{}, onStruct func(reflect.StructField) error, onProperty func(reflect.StructField, *reflect.Value) error) error { // nolint: gocyclo
+// ScanContext scans the properties of the given object struct passing the context to each property
+func ScanContext(ctx context.Context, obj interface{}, onProperty func(context.Context, reflect.StructField, *reflect.Value) error) error {
+	return ScanAllContext(ctx, obj, func(context.Context, reflect.StructField) error { return nil }, onProperty)
+}
+
+// ScanAll scans each structs attribute, fields with the `strct:"-"` tag are skipped as well as channel and func fields
+func ScanAll(obj interface{}, onStruct func(reflect.StructField) error, onProperty func(reflect.StructField, *reflect.Value) error) error {
+	return ScanAllContext(context.Background(), obj,
+		func(_ context.Context, f reflect.StructField) error { return onStruct(f) },
+		func(_ context.Context, f reflect.StructField, v *reflect.Value) error { return onProperty(f, v) },
+	)
+}
+
+// ScanAllContext scans each structs attribute passing the context to each callback and stops scanning once the context is done
+func ScanAllContext(ctx context.Context, obj interface{}, onStruct func(context.Context, reflect.StructField) error, onProperty func(context.Context, reflect.StructField, *reflect.Value) error) error {
+	s := &scanner{
+		onStruct: func(ctx context.Context, _ string, f reflect.StructField) error { return onStruct(ctx, f) },
+		onProperty: func(ctx context.Context, _ string, f reflect.StructField, v *reflect.Value) error {
+			return onProperty(ctx, f, v)
+		},
+	}
+
+	return s.scanObj(ctx, obj)
+}
+
+// ScanAllErrors scans each structs attribute like ScanAll but continues after a callback returns an error,
+// the errors prefixed with the path of their field are returned combined as a single error
+func ScanAllErrors(obj interface{}, onStruct func(reflect.StructField) error, onProperty func(reflect.StructField, *reflect.Value) error) error {
+	s := &scanner{
+		collect:  true,
+		onStruct: func(_ context.Context, _ string, f reflect.StructField) error { return onStruct(f) },
+		onProperty: func(_ context.Context, _ string, f reflect.StructField, v *reflect.Value) error {
+			return onProperty(f, v)
+		},
+	}
+
+	if err := s.scanObj(context.Background(), obj); err != nil {
+		return err
+	}
+
+	if len(s.errs) == 0 {
+		return nil
+	}
+
+	return s.errs
+}
+
+// scanErrors combines the errors of a scan like errors.Join does, errors.Is and errors.As match each of them
+// through the Is and As methods since Go versions before 1.20 don't unwrap multiple errors
+type scanErrors []error
+
+func (e scanErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+func (e scanErrors) Unwrap() []error {
+	return e
+}
+
+func (e scanErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e scanErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ScanOptions configures the way ScanWith scans an object struct
+type ScanOptions struct {
+	// PrefixTag is the tag of which the value of each property is prefixed with the value of the same tag
+	// of the structs it's nested in
+	PrefixTag string
+
+	// StrictTags are the tags which return ErrUnexported if an unexported field has one of them
+	StrictTags []string
+
+	// Validate is called with the value of each property after it's scanned, an error stops the scan
+	Validate func(reflect.StructField, reflect.Value) error
+
+	// OnStruct is called with the dotted path within the object struct of each nested struct before it's scanned,
+	// where the root's properties have a single segment path like Database and deeper ones a path like Database.Primary
+	OnStruct func(path string, field reflect.StructField) error
+
+	// MaxDepth is the number of levels of nested structs that are scanned where 1 only scans the top level
+	// properties, the properties at the deepest level are scanned without recursing into them. Zero scans all levels
+	MaxDepth int
+}
+
+// ScanWith scans the properties of the given object struct using the given options
+func ScanWith(obj interface{}, opts ScanOptions, onProperty func(reflect.StructField, *reflect.Value) error) error {
+	s := &scanner{
+		opts: opts,
+		onStruct: func(_ context.Context, path string, f reflect.StructField) error {
+			if opts.OnStruct == nil {
+				return nil
+			}
+
+			return opts.OnStruct(path, f)
+		},
+		onProperty: func(_ context.Context, _ string, f reflect.StructField, v *reflect.Value) error {
+			return onProperty(f, v)
+		},
+	}
+
+	return s.scanObj(context.Background(), obj)
+}
+
+// ScanValidate scans the properties of the given object struct and validates each property after it's scanned
+func ScanValidate(obj interface{}, onProperty func(reflect.StructField, *reflect.Value) error, onValidate func(reflect.StructField, reflect.Value) error) error {
+	return ScanWith(obj, ScanOptions{Validate: onValidate}, onProperty)
+}
+
+// ScanDepth scans the properties of the given object struct up to the given depth where 0 only scans
+// the top level properties without recursing into nested structs
+func ScanDepth(obj interface{}, depth int, onProperty func(reflect.StructField, *reflect.Value) error) error {
+	return ScanWith(obj, ScanOptions{MaxDepth: depth + 1}, onProperty)
+}
+
+// ScanReadOnly scans the properties of the given object struct which may also be passed by value and passes
+// properties which aren't settable to onProperty as well, the object struct itself isn't modified by the scan.
+// The properties of a struct passed by value aren't settable except for those reached through a slice element
+// or a pointer, since those share their memory with the caller and remain settable
+func ScanReadOnly(obj interface{}, onProperty func(reflect.StructField, *reflect.Value) error) error {
+	s := &scanner{
+		readOnly: true,
+		onStruct: func(context.Context, string, reflect.StructField) error { return nil },
+		onProperty: func(_ context.Context, _ string, f reflect.StructField, v *reflect.Value) error {
+			return onProperty(f, v)
+		},
+	}
+
+	return s.scanObj(context.Background(), obj)
+}
+
+// ScanFilter scans the properties of the given object struct calling onProperty only for the properties for which
+// include returns true, nested structs are scanned regardless of include unless they're skipped with `strct:"-"`
+func ScanFilter(obj interface{}, include func(reflect.StructField) bool, onProperty func(reflect.StructField, *reflect.Value) error) error {
+	return Scan(obj, func(f reflect.StructField, v *reflect.Value) error {
+		if !include(f) {
+			return nil
+		}
+
+		return onProperty(f, v)
+	})
+}
+
+// ScanPrefixed scans the properties of the given object struct where the given tag of each property
+// is prefixed with the value of the same tag of the structs it's nested in
+func ScanPrefixed(obj interface{}, tag string, onProperty func(reflect.StructField, *reflect.Value) error) error {
+	return ScanWith(obj, ScanOptions{PrefixTag: tag}, onProperty)
+}
+
+type scanner struct {
+	opts       ScanOptions
+	onStruct   func(ctx context.Context, path string, field reflect.StructField) error
+	onProperty func(ctx context.Context, path string, field reflect.StructField, value *reflect.Value) error
+
+	// collect gathers the errors of the callbacks in errs instead of stopping the scan
+	collect bool
+	errs    scanErrors
+
+	// readOnly passes properties which aren't settable to onProperty and leaves the scanned object untouched
+	readOnly bool
+
+	// visiting holds the structs currently being scanned to detect cycles
+	visiting map[visit]bool
+}
+
+// fail prefixes the error with the path of its field and returns it, or gathers it when collecting errors
+func (s *scanner) fail(path string, err error) error {
+	err = fmt.Errorf(`%s: %w`, path, err)
+	if !s.collect {
+		return err
+	}
+
+	s.errs = append(s.errs, err)
+	return nil
+}
+
+// visit identifies a struct by its address and type since embedded structs share the address of their parent
+type visit struct {
+	addr uintptr
+	typ  reflect.Type
+}
+
+// scope describes where a struct is nested within the scanned object
+type scope struct {
+	path   string
+	prefix string
+	depth  int
+}
+
+// child returns the scope of a struct nested in the given field
+func (s *scanner) child(sc scope, path string, field reflect.StructField) scope {
+	// the tag of the field already holds the inherited prefix, untagged fields pass the inherited prefix on
+	if v, ok := field.Tag.Lookup(s.opts.PrefixTag); ok && s.opts.PrefixTag != `` {
+		sc.prefix = v
+	}
+
+	sc.path = path
+	sc.depth++
+	return sc
+}
+
+// deeper reports if the structs nested in the given scope should be scanned
+func (s *scanner) deeper(sc scope) bool {
+	return s.opts.MaxDepth == 0 || sc.depth+1 < s.opts.MaxDepth
+}
+
+func (s *scanner) scanObj(ctx context.Context, obj interface{}) error {
 	rv := reflect.ValueOf(obj)
-	if rv.Kind() != reflect.Ptr || rv.IsNil() {
-		return ErrNoPtr
+	switch {
+	case s.readOnly && rv.Kind() == reflect.Struct:
+		return s.scan(ctx, rv, scope{})
+	case s.readOnly && rv.Kind() != reflect.Ptr:
+		return ErrNotStruct
+	case rv.Kind() != reflect.Ptr:
+		return ErrNotPtr
+	case rv.IsNil():
+		return ErrNilPtr
 	}
 
 	rv = rv.Elem()
 	if rv.Kind() != reflect.Struct {
-		return ErrNoPtr
+		return ErrNotStruct
 	}
 
+	return s.scan(ctx, rv, scope{})
+}
+
+func (s *scanner) scan(ctx context.Context, rv reflect.Value, sc scope) error { // nolint: gocyclo
 	t := rv.Type()
-	for i := 0; i < rv.NumField(); i++ {
-		f := rv.Field(i)
+
+	// structs passed by value can't be part of a cycle until a pointer is followed
+	if rv.CanAddr() {
+		v := visit{rv.UnsafeAddr(), t}
+		if s.visiting[v] {
+			return nil
+		}
+
+		if s.visiting == nil {
+			s.visiting = map[visit]bool{}
+		}
+		s.visiting[v] = true
+		defer delete(s.visiting, v)
+	}
+
+	for _, cf := range structFields(t) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		field := s.field(cf.field, sc.prefix)
+		path := joinPath(sc.path, field.Name)
+		if s.strict(field) {
+			if err := s.fail(path, ErrUnexported); err != nil {
+				return err
+			}
+			continue
+		}
+
+		f := rv.Field(cf.index)
 		switch f.Kind() {
 		case reflect.Ptr:
 			if f.Elem().Kind() != reflect.Struct {
@@ -74,51 +415,608 @@ func ScanAll(obj interface{}, onStruct func(reflect.StructField) error, onProper
 			fallthrough
 
 		case reflect.Struct:
-			if !f.Addr().CanInterface() {
+			// the exported fields of an embedded struct are settable even if the struct itself is unexported
+			if !f.CanInterface() && !cf.embedded {
+				continue
+			}
+
+			if isValueType(f.Type()) || !s.deeper(sc) {
+				break
+			}
+
+			if err := s.onStruct(ctx, path, field); err != nil {
+				if err := s.fail(path, err); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := s.scan(ctx, f, s.child(sc, path, field)); err != nil {
+				return err
+			}
+
+		case reflect.Slice:
+			if !f.CanInterface() {
 				continue
 			}
 
-			if err := onStruct(t.Field(i)); err != nil {
+			if !s.deeper(sc) {
+				break
+			}
+
+			if err := s.scanSlice(ctx, field, f, s.child(sc, path, field)); err != nil {
 				return err
 			}
 
-			if err := ScanAll(f.Addr().Interface(), onStruct, onProperty); err != nil {
+		case reflect.Map:
+			if !f.CanInterface() {
+				continue
+			}
+
+			if !s.deeper(sc) {
+				break
+			}
+
+			if err := s.scanMap(ctx, field, f, s.child(sc, path, field)); err != nil {
+				return err
+			}
+
+		}
+
+		usable := f.CanSet()
+		if s.readOnly {
+			usable = f.CanInterface()
+		}
+
+		if !usable {
+			continue
+		}
+
+		if err := s.onProperty(ctx, path, field, &f); err != nil {
+			if err := s.fail(path, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if s.opts.Validate == nil {
+			continue
+		}
+
+		if err := s.opts.Validate(field, f); err != nil {
+			if err := s.fail(path, err); err != nil {
+				return err
+			}
+		}
+
+	}
+	return nil
+}
+
+// scanSlice scans each struct of a slice of structs or pointers to structs
+func (s *scanner) scanSlice(ctx context.Context, field reflect.StructField, f reflect.Value, sc scope) error {
+	path := sc.path
+	for i := 0; i < f.Len(); i++ {
+		elem := f.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				continue
+			}
+
+			elem = elem.Elem()
+		}
+
+		if elem.Kind() != reflect.Struct || isValueType(elem.Type()) {
+			return nil
+		}
+
+		sc.path = fmt.Sprintf(`%s[%d]`, path, i)
+		if err := s.onStruct(ctx, sc.path, field); err != nil {
+			if err := s.fail(sc.path, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := s.scan(ctx, elem, sc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// strict reports if the field is unexported while having one of the strict tags
+func (s *scanner) strict(field reflect.StructField) bool {
+	if field.PkgPath == `` || field.Anonymous {
+		return false
+	}
+
+	for _, tag := range s.opts.StrictTags {
+		if _, ok := field.Tag.Lookup(tag); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scanMap scans each struct of a map of structs or pointers to structs, since map values aren't addressable
+// struct values are copied, scanned and set back onto the map
+func (s *scanner) scanMap(ctx context.Context, field reflect.StructField, f reflect.Value, sc scope) error {
+	keys := f.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+
+	path := sc.path
+	for _, key := range keys {
+		elem := f.MapIndex(key)
+		copied := false
+		switch elem.Kind() {
+		case reflect.Ptr:
+			if elem.IsNil() {
+				continue
+			}
+
+			elem = elem.Elem()
+		case reflect.Struct:
+			if s.readOnly {
+				break
+			}
+
+			v := reflect.New(elem.Type()).Elem()
+			v.Set(elem)
+			elem = v
+			copied = true
+		}
+
+		if elem.Kind() != reflect.Struct || isValueType(elem.Type()) {
+			return nil
+		}
+
+		sc.path = fmt.Sprintf(`%s[%v]`, path, key)
+		if err := s.onStruct(ctx, sc.path, field); err != nil {
+			if err := s.fail(sc.path, err); err != nil {
 				return err
 			}
+			continue
+		}
+
+		err := s.scan(ctx, elem, sc)
+		if copied {
+			f.SetMapIndex(key, elem)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// field prefixes the prefix tag of the field with the prefix of the struct it's nested in
+func (s *scanner) field(field reflect.StructField, prefix string) reflect.StructField {
+	if s.opts.PrefixTag == `` || prefix == `` {
+		return field
+	}
+
+	val, ok := field.Tag.Lookup(s.opts.PrefixTag)
+	if !ok {
+		return field
+	}
+
+	// reflect.StructTag.Get returns the first occurrence of a key so prepending overrides the original value
+	field.Tag = reflect.StructTag(fmt.Sprintf(`%s:%q %s`, s.opts.PrefixTag, prefix+val, field.Tag))
+	return field
+}
+
+func joinPath(path, name string) string {
+	if path == `` {
+		return name
+	}
+
+	return path + `.` + name
+}
+
+// Formats of values which can't be derived from the type
+const (
+	// FormatJSON is the format for values that should be unmarshaled as json
+	FormatJSON = `json`
+
+	// FormatISO8601 is the format for durations like PT1H30M instead of 1h30m
+	FormatISO8601 = `iso8601`
+
+	// FormatBytes is the format for byte counts with a decimal or binary size suffix like 10MB or 2KiB
+	FormatBytes = `bytes`
+)
+
+// Encodings a value of a byte slice can be decoded from
+const (
+	EncodingBase64 = `base64`
+	EncodingHex    = `hex`
+)
+
+// DefaultDriver is the sql driver used for a *sql.DB when the value doesn't specify a driver
+const DefaultDriver = `postgres`
+
+var (
+	defaultDriverMu sync.RWMutex
+	defaultDriver   = DefaultDriver
+)
+
+// SetDefaultDriver sets the sql driver used for a *sql.DB when the value doesn't specify a driver,
+// an empty name resets it to DefaultDriver
+func SetDefaultDriver(name string) {
+	if name == `` {
+		name = DefaultDriver
+	}
+
+	defaultDriverMu.Lock()
+	defaultDriver = name
+	defaultDriverMu.Unlock()
+}
+
+// DefaultSliceDelimiter is the delimiter used to split slices and maps when no other delimiter is given
+const DefaultSliceDelimiter = `;`
+
+var defaultNestedDelimiters = []string{`,`, `|`}
+
+// ParseOptions configures the way ParseWith sets a value
+type ParseOptions struct {
+	// Overwrite sets the value even if the reflected value already holds a value
+	Overwrite bool
+
+	// ShouldOverwrite reports if the reflected value should be set when Overwrite is false,
+	// defaults to overwriting zero values only
+	ShouldOverwrite func(reflect.Value) bool
+
+	// SliceDelimiter separates the elements of a slice and the entries of a map, defaults to DefaultSliceDelimiter
+	SliceDelimiter string
+
+	// NestedDelimiters separate the elements of nested slices, the first delimiter is used one level deep,
+	// the second two levels deep and so on. Defaults to `,` and `|`
+	NestedDelimiters []string
+
+	// TimeLayout is the layout used to parse time.Time values, defaults to time.RFC3339
+	TimeLayout string
+
+	// PingDatabase pings a parsed *sql.DB to validate the connection
+	PingDatabase bool
+
+	// OpenDatabase returns the *sql.DB for the value of a *sql.DB field instead of opening a new one, which allows
+	// fields to share a pool. The returned database isn't closed by Close or after a failed ping, defaults to sql.Open
+	OpenDatabase func(val string) (*sql.DB, error)
+
+	// Format is the format of the value, FormatJSON unmarshals the value as json, FormatISO8601 parses
+	// durations as ISO 8601 durations and FormatBytes parses numbers with a size suffix as byte count
+	Format string
+
+	// ResolveHosts looks up the host names of tcp addresses, without it only ip addresses are accepted
+	ResolveHosts bool
+
+	// StrictNumbers returns an error instead of setting a float which can't represent the value exactly, like
+	// 0.123456789 for a float32, or a byte size with a fraction of a byte. Out of range numbers always return an error
+	StrictNumbers bool
+
+	// Unquote strips the double quotes surrounding a string value keeping the whitespace within them, a double quote
+	// within the quotes is escaped by doubling it like the elements of a slice. Unquoted values are set as is
+	Unquote bool
+
+	// ReadFile uses the value as path of a file of which the contents are parsed instead, like secrets mounted as files.
+	// A single trailing newline is stripped from the contents
+	ReadFile bool
+
+	// ExpandEnv replaces ${var} or $var in the value with the environment variable before parsing it
+	ExpandEnv bool
+
+	// Encoding is the encoding used to decode byte slices, either EncodingBase64 or EncodingHex, byte slices are
+	// set from the raw value if empty
+	Encoding string
+
+	depth int
+}
+
+func (o ParseOptions) timeLayout() string {
+	if o.TimeLayout == `` {
+		return time.RFC3339
+	}
 
+	return o.TimeLayout
+}
+
+func (o ParseOptions) sliceDelimiter() (string, error) {
+	if o.depth == 0 {
+		if o.SliceDelimiter == `` {
+			return DefaultSliceDelimiter, nil
 		}
 
-		if !f.CanSet() {
+		return o.SliceDelimiter, nil
+	}
+
+	nested := o.NestedDelimiters
+	if nested == nil {
+		nested = defaultNestedDelimiters
+	}
+
+	if o.depth > len(nested) {
+		return ``, fmt.Errorf(`no delimiter configured for nesting depth %d`, o.depth)
+	}
+
+	return nested[o.depth-1], nil
+}
+
+func (o ParseOptions) shouldOverwrite(fv *reflect.Value) bool {
+	if o.Overwrite {
+		return true
+	}
+
+	if o.ShouldOverwrite != nil {
+		return o.ShouldOverwrite(*fv)
+	}
+
+	return isUnset(fv)
+}
+
+// nested returns the options for the elements of a slice, array or map which are always newly created
+func (o ParseOptions) nested() ParseOptions {
+	o.depth++
+	o.Overwrite = true
+	// elements of strings are already unquoted while splitting them
+	o.Unquote = false
+	return o
+}
+
+// Parse sets a string as value to the the reflected value if it holds the zero value of its type,
+// numbers follow the go syntax for literals so prefixes like 0x and underscores like 1_000 are accepted
+// except for os.FileMode which is always octal so 644, 0644, 0o644 and 0o6_44 are the same
+func Parse(val string, fv *reflect.Value) error {
+	return ParseWith(val, fv, ParseOptions{})
+}
+
+// ParseHard sets a string as value to the given value and overides previous values
+func ParseHard(val string, fv *reflect.Value) error {
+	return ParseWith(val, fv, ParseOptions{Overwrite: true})
+}
+
+// ParseField sets a string as value to the reflected value of the given field using the field's tags,
+// the reflected value doesn't have to be scanned as long as it's settable. The supported tags are:
+//
+//	timeformat:"2006-01-02"  sets the layout of a time.Time field
+//	db:"ping"                pings a *sql.DB field after opening it
+//	format:"json"            unmarshals the value as json
+//	format:"iso8601"         parses a time.Duration as ISO 8601 duration like PT1H30M
+//	format:"bytes"           parses an integer from a byte size like 10MB or 2KiB
+//	encoding:"base64"        decodes a byte slice from base64 or hex
+//	sep:":"                  separates the elements of a slice or the entries of a map
+//	resolve:"true"           resolves the host name of a *net.TCPAddr
+//	quoted:"true"            strips the double quotes surrounding a string keeping its whitespace
+//	source:"file"            reads the value from the file at the path given as value without its trailing newline
+//	required:"true"          returns ErrRequired if the value is empty and the field isn't set
+//	oneof:"debug info"       returns ErrNotAllowed if a string isn't one of the case sensitive values
+func ParseField(field reflect.StructField, val string, fv *reflect.Value) error {
+	return ParseFieldWith(field, val, fv, ParseOptions{})
+}
+
+// ParseFieldWith sets a string as value to the reflected value of the given field using the given options
+// where the field's tags take precedence over the options
+func ParseFieldWith(field reflect.StructField, val string, fv *reflect.Value, opts ParseOptions) error {
+	opts = fieldOptions(field, opts)
+	if opts.ExpandEnv {
+		// expand before the required check so a reference to an unset variable counts as empty
+		val, opts.ExpandEnv = os.ExpandEnv(val), false
+	}
+
+	if val == `` && field.Tag.Get(`required`) == `true` && isUnset(fv) {
+		return fmt.Errorf(`%w: %s`, ErrRequired, field.Name)
+	}
+
+	if err := ParseWith(val, fv, opts); err != nil {
+		return err
+	}
+
+	return oneOf(field, fv)
+}
+
+// oneOf returns ErrNotAllowed if the string field holds a value which isn't one of the space separated values
+// of its oneof tag, the values are case sensitive and an empty field is allowed
+func oneOf(field reflect.StructField, fv *reflect.Value) error {
+	allowed, ok := field.Tag.Lookup(`oneof`)
+	if !ok || fv.Kind() != reflect.String || fv.String() == `` {
+		return nil
+	}
+
+	values := strings.Fields(allowed)
+	for _, v := range values {
+		if fv.String() == v {
+			return nil
+		}
+	}
+
+	return fmt.Errorf(`%w: %s is %q but must be one of %s`, ErrNotAllowed, field.Name, fv.String(), strings.Join(values, `, `))
+}
+
+// fieldOptions overrides the options with the tags of the field
+func fieldOptions(field reflect.StructField, opts ParseOptions) ParseOptions {
+	if v, ok := field.Tag.Lookup(`timeformat`); ok {
+		opts.TimeLayout = v
+	}
+
+	if field.Tag.Get(`db`) == `ping` {
+		opts.PingDatabase = true
+	}
+
+	if v, ok := field.Tag.Lookup(`format`); ok {
+		opts.Format = v
+	}
+
+	if v, ok := field.Tag.Lookup(`encoding`); ok {
+		opts.Encoding = v
+	}
+
+	if v := field.Tag.Get(`sep`); v != `` {
+		opts.SliceDelimiter = v
+	}
+
+	if field.Tag.Get(`resolve`) == `true` {
+		opts.ResolveHosts = true
+	}
+
+	if field.Tag.Get(`quoted`) == `true` {
+		opts.Unquote = true
+	}
+
+	if field.Tag.Get(`source`) == `file` {
+		opts.ReadFile = true
+	}
+
+	return opts
+}
+
+// ParseAll scans the given object struct and parses the value of the given tag onto each property
+// without overwriting properties that are already set
+func ParseAll(obj interface{}, tag string) error {
+	return Scan(obj, func(field reflect.StructField, value *reflect.Value) error {
+		return ParseField(field, field.Tag.Get(tag), value)
+	})
+}
+
+// ParseTags resolves the value of each of the given tags of the field through lookup in the given order and
+// parses the first non empty result onto the field, the field isn't overwritten if it already holds a value
+func ParseTags(field reflect.StructField, value *reflect.Value, lookup func(key string) (string, bool), tags ...string) error {
+	for _, tag := range tags {
+		key := field.Tag.Get(tag)
+		if key == `` {
 			continue
 		}
 
-		if err := onProperty(t.Field(i), &f); err != nil {
-			return err
+		if val, ok := lookup(key); ok && val != `` {
+			return ParseField(field, val, value)
+		}
+	}
+
+	return ParseField(field, ``, value)
+}
+
+// ApplyDefaults parses the value of the `default` tag onto each property of the given object struct
+// without overwriting properties that are already set
+func ApplyDefaults(obj interface{}) error {
+	return ParseAll(obj, `default`)
+}
+
+// ParseWith sets a string as value to the reflected value using the given options, an empty interface is set
+// to a bool, int, float64 or string depending on which of them the value looks like first
+func ParseWith(val string, fv *reflect.Value, opts ParseOptions) error { // nolint: gocyclo
+	if !opts.shouldOverwrite(fv) {
+		return nil
+	}
+
+	if opts.ExpandEnv {
+		val = os.ExpandEnv(val)
+		// expand only once so environment variables containing a $ are kept as is
+		opts.ExpandEnv = false
+	}
+
+	if val == `` {
+		return nil
+	}
+
+	if opts.ReadFile {
+		b, err := ioutil.ReadFile(val)
+		if err != nil {
+			return err
+		}
+
+		val = string(b)
+		if strings.HasSuffix(val, "\n") {
+			// files usually end with a newline which isn't part of the value
+			val = strings.TrimSuffix(strings.TrimSuffix(val, "\n"), "\r")
+		}
+
+		// read only once so the elements of a slice aren't read as paths
+		opts.ReadFile = false
+	}
+
+	if ok, err := parseRegistered(val, fv); ok {
+		return err
+	}
+
+	if ok, err := parseInterfaceImpl(val, fv, opts); ok {
+		return err
+	}
+
+	if nullTypes[fv.Type()] {
+		return parseNull(val, fv, opts)
+	}
+
+	switch fv.Type() {
+	case timeType, reflect.PtrTo(timeType):
+		v, err := time.Parse(opts.timeLayout(), val)
+		if err != nil {
+			return err
+		}
+		setPtr(fv, reflect.ValueOf(&v))
+		return nil
+
+	case ipType:
+		ip := net.ParseIP(val)
+		if ip == nil {
+			return fmt.Errorf(`invalid ip address: %s`, val)
+		}
+		fv.Set(reflect.ValueOf(ip))
+		return nil
+
+	case urlType, urlType.Elem():
+		u, err := url.Parse(val)
+		if err != nil {
+			return err
+		}
+		setPtr(fv, reflect.ValueOf(u))
+		return nil
+
+	case tcpAddrType, tcpAddrType.Elem():
+		addr, err := parseTCPAddr(val, opts.ResolveHosts)
+		if err != nil {
+			return err
+		}
+		setPtr(fv, reflect.ValueOf(addr))
+		return nil
+
+	case bigIntType, bigIntType.Elem():
+		v, ok := new(big.Int).SetString(val, 0)
+		if !ok {
+			return fmt.Errorf(`invalid big integer: %s`, val)
+		}
+		setPtr(fv, reflect.ValueOf(v))
+		return nil
+
+	case bigFloatType, bigFloatType.Elem():
+		v, ok := new(big.Float).SetString(val)
+		if !ok {
+			return fmt.Errorf(`invalid big float: %s`, val)
 		}
+		setPtr(fv, reflect.ValueOf(v))
+		return nil
+	}
 
+	if opts.Format == FormatJSON {
+		return parseJSON(val, fv)
 	}
-	return nil
-}
 
-// Parse sets a string as value to the the reflected value
-func Parse(val string, fv *reflect.Value) error {
-	switch fmt.Sprint(fv.Interface()) {
-	case `false`, `0`, `[]`, ``, `<nil>`:
-		return ParseHard(val, fv)
-	default:
-		return nil
+	if ok, err := parseTextUnmarshaler(val, fv); ok {
+		return err
 	}
-}
 
-// ParseHard sets a string as value to the given value and overides previous values
-func ParseHard(val string, fv *reflect.Value) error { // nolint: gocyclo
-	if val == `` {
-		return nil
+	if fv.Type().Implements(jsonUnmarshalerType) || reflect.PtrTo(fv.Type()).Implements(jsonUnmarshalerType) {
+		return parseJSON(val, fv)
+	}
+
+	if ok, err := parseFlagValue(val, fv); ok {
+		return err
 	}
 
 	switch fv.Kind() {
 	case reflect.Bool:
-		v, err := strconv.ParseBool(val)
+		v, err := parseBool(val)
 		if err != nil {
 			return err
 		}
@@ -129,11 +1027,35 @@ func ParseHard(val string, fv *reflect.Value) error { // nolint: gocyclo
 		if err != nil {
 			return err
 		}
+
+		if opts.StrictNumbers && !exactFloat(val, v, fv.Type().Bits()) {
+			return fmt.Errorf(`%s can't be represented exactly as %s`, val, fv.Type())
+		}
 		fv.SetFloat(v)
 
+	case reflect.Complex64, reflect.Complex128:
+		v, err := strconv.ParseComplex(val, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetComplex(v)
+
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if opts.Format == FormatBytes {
+			n, err := parseByteSize(val, opts.StrictNumbers)
+			if err != nil {
+				return err
+			}
+			val = n
+		}
+
 		if t := fv.Type(); t.PkgPath() == `time` && t.Name() == `Duration` {
-			v, err := time.ParseDuration(val)
+			parse := time.ParseDuration
+			if opts.Format == FormatISO8601 {
+				parse = parseISODuration
+			}
+
+			v, err := parse(val)
 			if err != nil {
 				return err
 			}
@@ -147,6 +1069,20 @@ func ParseHard(val string, fv *reflect.Value) error { // nolint: gocyclo
 		}
 
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if opts.Format == FormatBytes {
+			n, err := parseByteSize(val, opts.StrictNumbers)
+			if err != nil {
+				return err
+			}
+			val = n
+		}
+
+		if fv.Type() == fileModeType {
+			// file modes are octal with or without the 0 or 0o prefix, the 0o prefix is added back
+			// so underscores are accepted like in go literals
+			val = `0o` + strings.TrimPrefix(strings.TrimPrefix(val, `0o`), `0O`)
+		}
+
 		v, err := strconv.ParseUint(val, 0, fv.Type().Bits())
 		if err != nil {
 			return err
@@ -154,20 +1090,95 @@ func ParseHard(val string, fv *reflect.Value) error { // nolint: gocyclo
 		fv.SetUint(v)
 
 	case reflect.String:
+		if opts.Unquote {
+			v, err := unquote(val)
+			if err != nil {
+				return err
+			}
+			val = v
+		}
 		fv.SetString(val)
 
 	case reflect.Slice:
-		parts := strings.Split(val, `;`)
+		if fv.Type().Elem() == byteType {
+			b, err := decodeBytes(val, opts.Encoding)
+			if err != nil {
+				return err
+			}
+			fv.SetBytes(b)
+			break
+		}
+
+		delim, err := opts.sliceDelimiter()
+		if err != nil {
+			return err
+		}
+
+		parts, err := splitElements(val, delim, fv.Type().Elem())
+		if err != nil {
+			return err
+		}
+
 		slice := reflect.MakeSlice(fv.Type(), len(parts), len(parts))
 		for i, part := range parts {
-			part = strings.TrimSpace(part)
 			in := slice.Index(i)
-			if err := Parse(part, &in); err != nil {
+			if err := ParseWith(part, &in, opts.nested()); err != nil {
 				return err
 			}
 		}
 		fv.Set(slice)
 
+	case reflect.Array:
+		delim, err := opts.sliceDelimiter()
+		if err != nil {
+			return err
+		}
+
+		parts, err := splitElements(val, delim, fv.Type().Elem())
+		if err != nil {
+			return err
+		}
+
+		if len(parts) > fv.Len() {
+			return fmt.Errorf(`%d elements exceed the array length of %d`, len(parts), fv.Len())
+		}
+
+		arr := reflect.New(fv.Type()).Elem()
+		for i, part := range parts {
+			in := arr.Index(i)
+			if err := ParseWith(part, &in, opts.nested()); err != nil {
+				return err
+			}
+		}
+		fv.Set(arr)
+
+	case reflect.Map:
+		delim, err := opts.sliceDelimiter()
+		if err != nil {
+			return err
+		}
+
+		t := fv.Type()
+		m := reflect.MakeMap(t)
+		for _, entry := range strings.Split(val, delim) {
+			kv := strings.SplitN(entry, `=`, 2)
+			if len(kv) != 2 {
+				return fmt.Errorf(`map entry %q has no '=' separator`, entry)
+			}
+
+			key := reflect.New(t.Key()).Elem()
+			if err := ParseWith(strings.TrimSpace(kv[0]), &key, opts.nested()); err != nil {
+				return err
+			}
+
+			elem := reflect.New(t.Elem()).Elem()
+			if err := ParseWith(strings.TrimSpace(kv[1]), &elem, opts.nested()); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, elem)
+		}
+		fv.Set(m)
+
 	case reflect.Interface, reflect.Ptr:
 		switch fv.Type() {
 		case reflect.TypeOf(new(os.File)),
@@ -181,22 +1192,497 @@ func ParseHard(val string, fv *reflect.Value) error { // nolint: gocyclo
 			if err != nil {
 				return err
 			}
+			track(file)
 			fv.Set(reflect.ValueOf(file))
 		case reflect.TypeOf(new(sql.DB)):
-			m := regexp.MustCompile(`((\w+)\/)?([\w\W\d]+)`).FindStringSubmatch(val)
-			dvr := m[2]
-			cs := m[3]
+			db, opened, err := openDatabase(val, opts)
+			if err != nil {
+				return err
+			}
 
-			if dvr == `` {
-				dvr = `postgres`
+			if opts.PingDatabase {
+				if err := db.Ping(); err != nil {
+					if opened {
+						db.Close() // nolint: errcheck
+					}
+					return err
+				}
 			}
 
-			db, err := sql.Open(dvr, cs)
-			if err != nil {
-				return err
+			if opened {
+				track(db)
 			}
 			fv.Set(reflect.ValueOf(db))
+
+		case emptyInterfaceType:
+			fv.Set(reflect.ValueOf(inferValue(val)))
+
+		default:
+			if fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() != reflect.Struct {
+				return parsePtr(val, fv, opts)
+			}
+
+			return fmt.Errorf(`%w: %s`, ErrUnsupportedType, fv.Type())
+		}
+	}
+	return nil
+}
+
+// parseNull parses the value onto the value field of a sql null type and marks it as valid
+func parseNull(val string, fv *reflect.Value, opts ParseOptions) error {
+	v := fv.Field(0)
+	opts.Overwrite = true
+	if err := ParseWith(val, &v, opts); err != nil {
+		return err
+	}
+
+	fv.FieldByName(`Valid`).SetBool(true)
+	return nil
+}
+
+// setPtr sets the pointer onto the reflected value or the value it points to if the reflected value isn't a pointer,
+// which happens when the scanner dereferenced a pointer to a struct
+func setPtr(fv *reflect.Value, ptr reflect.Value) {
+	if fv.Kind() == reflect.Ptr {
+		fv.Set(ptr)
+		return
+	}
+
+	fv.Set(ptr.Elem())
+}
+
+// parsePtr parses the value onto the value the pointer points to and allocates it if the pointer is nil
+func parsePtr(val string, fv *reflect.Value, opts ParseOptions) error {
+	if !fv.IsNil() {
+		elem := fv.Elem()
+		return ParseWith(val, &elem, opts)
+	}
+
+	v := reflect.New(fv.Type().Elem())
+	elem := v.Elem()
+	if err := ParseWith(val, &elem, opts); err != nil {
+		return err
+	}
+
+	fv.Set(v)
+	return nil
+}
+
+// byteSizes are the multipliers of the size suffixes by their upper case spelling
+var byteSizes = map[string]uint64{
+	``:    1,
+	`B`:   1,
+	`KB`:  1e3,
+	`MB`:  1e6,
+	`GB`:  1e9,
+	`TB`:  1e12,
+	`KIB`: 1 << 10,
+	`MIB`: 1 << 20,
+	`GIB`: 1 << 30,
+	`TIB`: 1 << 40,
+}
+
+// parseByteSize converts a size like 10MB, 1.5 GiB or 512 into the number of bytes it represents,
+// the suffixes are case insensitive and the count is returned as decimal string for the regular number parsing.
+// Fractions of a byte are rounded unless strict
+func parseByteSize(val string, strict bool) (string, error) {
+	i := strings.IndexFunc(val, func(r rune) bool { return !unicode.IsDigit(r) && r != '.' && r != '_' })
+	if i < 0 {
+		i = len(val)
+	}
+
+	mul, ok := byteSizes[strings.ToUpper(strings.TrimSpace(val[i:]))]
+	num, valid := stripUnderscores(val[:i])
+	if !ok || !valid || i == 0 {
+		return ``, fmt.Errorf(`invalid byte size: %s`, val)
+	}
+	if !strings.Contains(num, `.`) {
+		n, err := strconv.ParseUint(num, 10, 64)
+		if err != nil || n > math.MaxUint64/mul {
+			return ``, fmt.Errorf(`invalid byte size: %s`, val)
+		}
+
+		return strconv.FormatUint(n*mul, 10), nil
+	}
+
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || f*float64(mul) >= math.MaxUint64 {
+		return ``, fmt.Errorf(`invalid byte size: %s`, val)
+	}
+
+	n := math.Round(f * float64(mul))
+	if strict && n != f*float64(mul) {
+		return ``, fmt.Errorf(`byte size %s isn't a whole number of bytes`, val)
+	}
+
+	return strconv.FormatUint(uint64(n), 10), nil
+}
+
+// stripUnderscores removes the underscores separating the digits of a decimal number like 1_000,
+// it reports false if an underscore doesn't sit between two digits
+func stripUnderscores(num string) (string, bool) {
+	for i := 0; i < len(num); i++ {
+		if num[i] != '_' {
+			continue
+		}
+
+		if i == 0 || i == len(num)-1 || !unicode.IsDigit(rune(num[i-1])) || !unicode.IsDigit(rune(num[i+1])) {
+			return ``, false
+		}
+	}
+
+	return strings.Replace(num, `_`, ``, -1), true
+}
+
+// exactFloat reports if the float parsed from the decimal value is the exact number the value describes, which is
+// the case if the shortest representation of the float equals the value. Values which aren't decimals like NaN or
+// hexadecimal floats are always exact
+func exactFloat(val string, v float64, bits int) bool {
+	expected, ok := new(big.Rat).SetString(val)
+	if !ok {
+		return true
+	}
+
+	actual, ok := new(big.Rat).SetString(strconv.FormatFloat(v, 'g', -1, bits))
+	return !ok || expected.Cmp(actual) == 0
+}
+
+// parseISODuration parses an ISO 8601 duration like P1DT2H30M or -PT1.5S where a day is always 24 hours,
+// years and months are rejected since they don't have a fixed duration
+func parseISODuration(val string) (time.Duration, error) {
+	s := val
+	neg := strings.HasPrefix(s, `-`)
+	if neg || strings.HasPrefix(s, `+`) {
+		s = s[1:]
+	}
+
+	if !strings.HasPrefix(s, `P`) || len(s) == 1 {
+		return 0, fmt.Errorf(`invalid ISO 8601 duration: %s`, val)
+	}
+
+	// the designators have to be in the order of ISO 8601 without repetition and only the last one can have a fraction
+	var total float64
+	last := -1
+	inTime, fraction := false, false
+	for s = s[1:]; s != ``; {
+		if s[0] == 'T' {
+			if inTime || len(s) == 1 {
+				return 0, fmt.Errorf(`invalid ISO 8601 duration: %s`, val)
+			}
+
+			inTime = true
+			s = s[1:]
+			continue
+		}
+
+		i := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) && r != '.' && r != ',' })
+		if i <= 0 || fraction {
+			return 0, fmt.Errorf(`invalid ISO 8601 duration: %s`, val)
+		}
+
+		n, err := strconv.ParseFloat(strings.Replace(s[:i], `,`, `.`, 1), 64)
+		if err != nil {
+			return 0, fmt.Errorf(`invalid ISO 8601 duration: %s`, val)
+		}
+
+		var unit time.Duration
+		var rank int
+		switch c := s[i]; {
+		case !inTime && c == 'W':
+			unit, rank = 7*24*time.Hour, 0
+		case !inTime && c == 'D':
+			unit, rank = 24*time.Hour, 1
+		case !inTime && (c == 'Y' || c == 'M'):
+			return 0, fmt.Errorf(`years and months of ISO 8601 duration %s have no fixed duration`, val)
+		case inTime && c == 'H':
+			unit, rank = time.Hour, 2
+		case inTime && c == 'M':
+			unit, rank = time.Minute, 3
+		case inTime && c == 'S':
+			unit, rank = time.Second, 4
+		default:
+			return 0, fmt.Errorf(`invalid ISO 8601 duration: %s`, val)
+		}
+
+		if rank <= last {
+			return 0, fmt.Errorf(`invalid ISO 8601 duration: %s`, val)
+		}
+		last, fraction = rank, strings.ContainsAny(s[:i], `.,`)
+
+		total += n * float64(unit)
+		s = s[i+1:]
+	}
+
+	// like time.ParseDuration durations beyond the range of time.Duration are rejected
+	if total >= 1<<63 {
+		return 0, fmt.Errorf(`ISO 8601 duration %s out of range`, val)
+	}
+
+	d := time.Duration(total)
+	if neg {
+		d = -d
+	}
+
+	return d, nil
+}
+
+// parseBool parses human friendly booleans like yes, no, on, off, enabled and disabled case insensitively
+// before falling back to strconv.ParseBool
+func parseBool(val string) (bool, error) {
+	switch strings.ToLower(val) {
+	case `yes`, `y`, `on`, `enabled`, `enable`:
+		return true, nil
+	case `no`, `n`, `off`, `disabled`, `disable`:
+		return false, nil
+	}
+
+	v, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf(`invalid boolean %q: expected one of true, false, yes, no, on, off, enabled or disabled`, val)
+	}
+
+	return v, nil
+}
+
+// parseTCPAddr parses a host:port address where the host can be empty, an ip address or a host name which is only
+// resolved if resolve is set since looking up a host blocks and fails while DNS isn't available
+func parseTCPAddr(val string, resolve bool) (*net.TCPAddr, error) {
+	host, port, err := net.SplitHostPort(val)
+	if err != nil {
+		return nil, fmt.Errorf(`invalid tcp address %s: %w`, val, err)
+	}
+
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf(`invalid port %q of tcp address %s`, port, val)
+	}
+
+	if host == `` {
+		return &net.TCPAddr{Port: int(p)}, nil
+	}
+
+	ip, zone := host, ``
+	if i := strings.LastIndex(host, `%`); i >= 0 {
+		ip, zone = host[:i], host[i+1:]
+	}
+
+	if addr := net.ParseIP(ip); addr != nil {
+		return &net.TCPAddr{IP: addr, Port: int(p), Zone: zone}, nil
+	}
+
+	if !resolve {
+		return nil, fmt.Errorf(`host of tcp address %s isn't an ip address and host names aren't resolved`, val)
+	}
+
+	addr, err := net.ResolveIPAddr(`ip`, host)
+	if err != nil {
+		return nil, fmt.Errorf(`invalid host of tcp address %s: %w`, val, err)
+	}
+
+	return &net.TCPAddr{IP: addr.IP, Port: int(p), Zone: addr.Zone}, nil
+}
+
+// inferValue returns the value of an empty interface as the first type it can be parsed as: a bool for true or false
+// in any case, an int for decimal integers, a float64 for decimal numbers like 1.5 or 1e3 and a string otherwise.
+// So 1 is an int and never a bool, 1.0 a float64 and values like yes, 0x1f or NaN stay strings. Underscores between
+// digits are allowed in numbers so 1_000 is an int
+func inferValue(val string) interface{} {
+	switch strings.ToLower(val) {
+	case `true`:
+		return true
+	case `false`:
+		return false
+	}
+
+	num, ok := stripUnderscores(val)
+	if !ok {
+		return val
+	}
+
+	if v, err := strconv.Atoi(num); err == nil {
+		return v
+	}
+
+	decimal := strings.IndexFunc(num, unicode.IsDigit) >= 0 &&
+		strings.IndexFunc(num, func(r rune) bool { return unicode.IsLetter(r) && r != 'e' && r != 'E' }) < 0
+	if v, err := strconv.ParseFloat(num, 64); err == nil && decimal {
+		return v
+	}
+
+	return val
+}
+
+// unquote strips the double quotes surrounding the value where doubled quotes within them are a single quote
+func unquote(val string) (string, error) {
+	s := strings.TrimSpace(val)
+	if len(s) < 2 || !strings.HasPrefix(s, `"`) || !strings.HasSuffix(s, `"`) {
+		return val, nil
+	}
+
+	s = s[1 : len(s)-1]
+	if strings.Contains(strings.Replace(s, `""`, ``, -1), `"`) {
+		return ``, fmt.Errorf(`unescaped quote in quoted value %s`, val)
+	}
+
+	return strings.Replace(s, `""`, `"`, -1), nil
+}
+
+// splitElements splits the value of a slice or array into trimmed elements,
+// elements of strings can be quoted to contain the delimiter or surrounding whitespace
+func splitElements(val, delim string, elem reflect.Type) ([]string, error) {
+	if elem.Kind() != reflect.String {
+		parts := strings.Split(val, delim)
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+
+		return parts, nil
+	}
+
+	return splitQuoted(val, delim)
+}
+
+// splitQuoted splits the value on the delimiter except within double quotes, a double quote within
+// a quoted element is escaped by another double quote like in csv
+func splitQuoted(val, delim string) ([]string, error) {
+	var parts []string
+	for {
+		rest := strings.TrimLeftFunc(val, unicode.IsSpace)
+		if !strings.HasPrefix(rest, `"`) {
+			i := strings.Index(val, delim)
+			if i < 0 {
+				return append(parts, strings.TrimSpace(val)), nil
+			}
+
+			parts = append(parts, strings.TrimSpace(val[:i]))
+			val = val[i+len(delim):]
+			continue
+		}
+
+		var part strings.Builder
+		rest = rest[1:]
+		for {
+			i := strings.Index(rest, `"`)
+			if i < 0 {
+				return nil, fmt.Errorf(`unterminated quoted element in %q`, val)
+			}
+
+			part.WriteString(rest[:i])
+			rest = rest[i+1:]
+			if !strings.HasPrefix(rest, `"`) {
+				break
+			}
+
+			part.WriteString(`"`)
+			rest = rest[1:]
 		}
+		parts = append(parts, part.String())
+
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == `` {
+			return parts, nil
+		}
+
+		if !strings.HasPrefix(rest, delim) {
+			return nil, fmt.Errorf(`unexpected %q after quoted element`, rest)
+		}
+		val = rest[len(delim):]
+	}
+}
+
+// isUnset reports if the value is the zero value of its type, empty but non nil slices and maps are considered set
+func isUnset(fv *reflect.Value) bool {
+	return fv.IsZero()
+}
+
+// decodeBytes decodes the value of a byte slice, since []byte and []uint8 are the same type a []uint8 is parsed
+// as bytes as well, a slice of a named uint8 type is still parsed as a delimited list
+func decodeBytes(val, encoding string) ([]byte, error) {
+	switch encoding {
+	case ``:
+		return []byte(val), nil
+	case EncodingBase64:
+		return base64.StdEncoding.DecodeString(val)
+	case EncodingHex:
+		return hex.DecodeString(val)
+	default:
+		return nil, fmt.Errorf(`unknown encoding: %s`, encoding)
+	}
+}
+
+func parseJSON(val string, fv *reflect.Value) error {
+	v := reflect.New(fv.Type())
+	if err := json.Unmarshal([]byte(val), v.Interface()); err != nil {
+		return err
 	}
+
+	fv.Set(v.Elem())
 	return nil
 }
+
+func parseTextUnmarshaler(val string, fv *reflect.Value) (bool, error) {
+	return parseMethod(fv, textUnmarshalerType, func(v interface{}) error {
+		return v.(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+	})
+}
+
+func parseFlagValue(val string, fv *reflect.Value) (bool, error) {
+	return parseMethod(fv, flagValueType, func(v interface{}) error {
+		return v.(flag.Value).Set(val)
+	})
+}
+
+// parseMethod calls parse with the value or a pointer to the value if it implements the given interface,
+// nil pointers are allocated and only set if parse succeeds
+func parseMethod(fv *reflect.Value, iface reflect.Type, parse func(interface{}) error) (bool, error) {
+	if fv.Kind() == reflect.Ptr && fv.Type().Implements(iface) {
+		if !fv.IsNil() {
+			return true, parse(fv.Interface())
+		}
+
+		v := reflect.New(fv.Type().Elem())
+		if err := parse(v.Interface()); err != nil {
+			return true, err
+		}
+		fv.Set(v)
+		return true, nil
+	}
+
+	if fv.CanAddr() && fv.Addr().Type().Implements(iface) {
+		return true, parse(fv.Addr().Interface())
+	}
+
+	return false, nil
+}
+
+// openDatabase returns the database for the value and reports if it was opened by the parser
+func openDatabase(val string, opts ParseOptions) (*sql.DB, bool, error) {
+	if opts.OpenDatabase == nil {
+		db, err := sql.Open(parseConnString(val))
+		return db, true, err
+	}
+
+	db, err := opts.OpenDatabase(val)
+	if err == nil && db == nil {
+		err = fmt.Errorf(`no database for %s`, val)
+	}
+
+	return db, false, err
+}
+
+// parseConnString splits the driver from the connection string, the part before the first slash is only
+// used as driver if it's a registered driver otherwise the whole value is used as connection string
+func parseConnString(val string) (driver, conn string) {
+	if i := strings.Index(val, `/`); i > 0 {
+		for _, name := range sql.Drivers() {
+			if name == val[:i] {
+				return name, val[i+1:]
+			}
+		}
+	}
+
+	defaultDriverMu.RLock()
+	defer defaultDriverMu.RUnlock()
+
+	return defaultDriver, val
+}
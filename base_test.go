@@ -4,10 +4,23 @@ package strct
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 type testObj struct {
@@ -64,6 +77,1793 @@ func TestScanAndParse(t *testing.T) {
 
 }
 
+func TestParseHardMap(t *testing.T) {
+	var m map[string]int
+	fv := reflect.ValueOf(&m).Elem()
+
+	if err := ParseHard(`a=1; b = 2`, &fv); err != nil {
+		t.Fatal(err)
+	}
+	eq(map[string]int{`a`: 1, `b`: 2}, m, t)
+}
+
+func TestParseHardMapWithoutSeparator(t *testing.T) {
+	m := map[string]int{}
+	fv := reflect.ValueOf(&m).Elem()
+
+	if err := ParseHard(`a=1;b`, &fv); err == nil {
+		t.Error(`expected an error for a map entry without '='`)
+	}
+}
+
+func TestParseWithSliceDelimiter(t *testing.T) {
+	var sli []string
+	fv := reflect.ValueOf(&sli).Elem()
+
+	if err := ParseWith(`C:\\a;b, d`, &fv, ParseOptions{SliceDelimiter: `,`}); err != nil {
+		t.Fatal(err)
+	}
+	eq([]string{`C:\\a;b`, `d`}, sli, t)
+
+	if err := ParseWith(`1 || 2`, &fv, ParseOptions{Overwrite: true, SliceDelimiter: `||`}); err != nil {
+		t.Fatal(err)
+	}
+	eq([]string{`1`, `2`}, sli, t)
+}
+
+func TestParseNestedSlices(t *testing.T) {
+	var two [][]int
+	fv := reflect.ValueOf(&two).Elem()
+	if err := Parse(`1,2;3,4;5`, &fv); err != nil {
+		t.Fatal(err)
+	}
+	eq([][]int{{1, 2}, {3, 4}, {5}}, two, t)
+
+	var three [][][]int
+	fv = reflect.ValueOf(&three).Elem()
+	if err := Parse(`1|2,3;4,5|6`, &fv); err != nil {
+		t.Fatal(err)
+	}
+	eq([][][]int{{{1, 2}, {3}}, {{4}, {5, 6}}}, three, t)
+
+	var four [][][][]int
+	fv = reflect.ValueOf(&four).Elem()
+	if err := Parse(`1`, &fv); err == nil {
+		t.Error(`expected an error for a nesting depth without delimiter`)
+	}
+}
+
+func TestParseUnsupportedType(t *testing.T) {
+	var str fmt.Stringer
+	fv := reflect.ValueOf(&str).Elem()
+
+	if err := Parse(`test`, &fv); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("expected ErrUnsupportedType, got: %v", err)
+	}
+}
+
+type upperString string
+
+func (u *upperString) UnmarshalText(text []byte) error {
+	*u = upperString(strings.ToUpper(string(text)))
+	return nil
+}
+
+func TestParseTextUnmarshaler(t *testing.T) {
+	var obj struct {
+		Time  time.Time
+		Upper upperString
+		Ptr   *upperString
+	}
+
+	err := Scan(&obj, func(field reflect.StructField, value *reflect.Value) error {
+		switch field.Name {
+		case `Time`:
+			return Parse(`2019-10-12T07:20:50Z`, value)
+		default:
+			return Parse(`test`, value)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eq(time.Date(2019, 10, 12, 7, 20, 50, 0, time.UTC), obj.Time, t)
+	eq(`TEST`, obj.Upper, t)
+	if obj.Ptr == nil {
+		t.Fatal(`pointer not parsed`)
+	}
+	eq(`TEST`, *obj.Ptr, t)
+}
+
+func TestParseFieldTimeFormat(t *testing.T) {
+	var obj struct {
+		Default time.Time `default:"2019-10-12T07:20:50Z"`
+		Date    time.Time `default:"2019-10-12" timeformat:"2006-01-02"`
+		Invalid time.Time `timeformat:"2006-01-02"`
+	}
+
+	err := Scan(&obj, func(field reflect.StructField, value *reflect.Value) error {
+		return ParseField(field, field.Tag.Get(`default`), value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eq(time.Date(2019, 10, 12, 7, 20, 50, 0, time.UTC), obj.Default, t)
+	eq(time.Date(2019, 10, 12, 0, 0, 0, 0, time.UTC), obj.Date, t)
+
+	field, _ := reflect.TypeOf(obj).FieldByName(`Invalid`)
+	fv := reflect.ValueOf(&obj).Elem().FieldByName(`Invalid`)
+	if err := ParseField(field, `2019-10-12T07:20:50Z`, &fv); err == nil {
+		t.Error(`expected a time parse error`)
+	}
+}
+
+func TestScanContext(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, `value`)
+
+	var obj struct {
+		Str    string
+		Nested struct {
+			Str string
+		}
+	}
+
+	err := ScanContext(ctx, &obj, func(ctx context.Context, field reflect.StructField, value *reflect.Value) error {
+		return Parse(fmt.Sprint(ctx.Value(key{})), value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(`value`, obj.Str, t)
+	eq(`value`, obj.Nested.Str, t)
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	err = ScanContext(ctx, &obj, func(context.Context, reflect.StructField, *reflect.Value) error {
+		t.Error(`should not scan with a canceled context`)
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got: %v", err)
+	}
+}
+
+type testDriver struct{}
+
+func (testDriver) Open(name string) (driver.Conn, error) {
+	if name == `unreachable` {
+		return nil, fmt.Errorf(`connection refused`)
+	}
+
+	return testConn{}, nil
+}
+
+type testConn struct{}
+
+func (testConn) Prepare(query string) (driver.Stmt, error) { return nil, fmt.Errorf(`not implemented`) }
+func (testConn) Close() error                              { return nil }
+func (testConn) Begin() (driver.Tx, error)                 { return nil, fmt.Errorf(`not implemented`) }
+
+func init() {
+	sql.Register(`strcttest`, testDriver{})
+	sql.Register(`mysql`, testDriver{})
+}
+
+func TestParseFieldPingDatabase(t *testing.T) {
+	var obj struct {
+		DB   *sql.DB `db:"ping"`
+		Lazy *sql.DB
+	}
+
+	rv := reflect.ValueOf(&obj).Elem()
+	field, _ := rv.Type().FieldByName(`DB`)
+	fv := rv.FieldByName(`DB`)
+	if err := ParseField(field, `strcttest/unreachable`, &fv); err == nil {
+		t.Error(`expected a ping error`)
+	}
+
+	if obj.DB != nil {
+		t.Error(`database should not be set after a failed ping`)
+	}
+
+	if err := ParseField(field, `strcttest/reachable`, &fv); err != nil {
+		t.Error(err)
+	}
+
+	field, _ = rv.Type().FieldByName(`Lazy`)
+	fv = rv.FieldByName(`Lazy`)
+	if err := ParseField(field, `strcttest/unreachable`, &fv); err != nil {
+		t.Errorf("should not ping without the tag: %v", err)
+	}
+}
+
+func TestSetDefaultDriver(t *testing.T) {
+	SetDefaultDriver(`strcttest`)
+	defer SetDefaultDriver(``)
+
+	var db *sql.DB
+	fv := reflect.ValueOf(&db).Elem()
+	opts := ParseOptions{PingDatabase: true}
+	if err := ParseWith(`reachable`, &fv, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if db == nil {
+		t.Fatal(`database not parsed`)
+	}
+	db.Close() // nolint: errcheck
+
+	opts.Overwrite = true
+	if err := ParseWith(`strcttest/unreachable`, &fv, opts); err == nil {
+		t.Error(`expected the explicit driver to take precedence`)
+	}
+}
+
+func TestParseConnString(t *testing.T) {
+	tests := []struct {
+		val    string
+		driver string
+		conn   string
+	}{
+		{`postgres://user@host/db`, `postgres`, `postgres://user@host/db`},
+		{`host=localhost dbname=db sslmode=disable`, `postgres`, `host=localhost dbname=db sslmode=disable`},
+		{`host/dbname`, `postgres`, `host/dbname`},
+		{`mysql/user:pass@/db`, `mysql`, `user:pass@/db`},
+		{`strcttest/postgres://user@host/db`, `strcttest`, `postgres://user@host/db`},
+	}
+
+	for _, tt := range tests {
+		driver, conn := parseConnString(tt.val)
+		eq(tt.driver, driver, t)
+		eq(tt.conn, conn, t)
+	}
+}
+
+type testAddress struct {
+	Street string `env:"STREET"`
+	Number int    `env:"NUMBER" default:"1"`
+	Note   string
+}
+
+func TestScanPrefixed(t *testing.T) {
+	var obj struct {
+		Name        string `env:"NAME"`
+		testAddress `env:"ADDR_"`
+		Billing     struct {
+			Address testAddress `env:"ADDRESS_"`
+		} `env:"BILLING_"`
+		Shipping struct {
+			Untagged struct {
+				Address testAddress `env:"ADDRESS_"`
+			}
+		} `env:"SHIPPING_"`
+	}
+
+	tags := map[string]string{}
+	err := ScanPrefixed(&obj, `env`, func(field reflect.StructField, value *reflect.Value) error {
+		if v, ok := field.Tag.Lookup(`env`); ok {
+			tags[field.Name] += v + ` `
+		}
+
+		return Parse(field.Tag.Get(`default`), value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eq(`NAME `, tags[`Name`], t)
+	eq(`ADDR_STREET BILLING_ADDRESS_STREET SHIPPING_ADDRESS_STREET `, tags[`Street`], t)
+	eq(`ADDR_NUMBER BILLING_ADDRESS_NUMBER SHIPPING_ADDRESS_NUMBER `, tags[`Number`], t)
+	eq(``, tags[`Note`], t)
+	eq(1, obj.Billing.Address.Number, t)
+}
+
+type testPoint struct {
+	X, Y int
+}
+
+func (p *testPoint) UnmarshalJSON(b []byte) error {
+	var xy []int
+	if err := json.Unmarshal(b, &xy); err != nil {
+		return err
+	}
+
+	if len(xy) != 2 {
+		return fmt.Errorf(`expected 2 coordinates`)
+	}
+
+	p.X, p.Y = xy[0], xy[1]
+	return nil
+}
+
+func TestParseJSON(t *testing.T) {
+	var obj struct {
+		Point  testPoint
+		Server struct {
+			Host string
+			Port int
+		} `format:"json"`
+		Tags []string `format:"json"`
+	}
+
+	values := map[string]string{
+		`Point`:  `[1, 2]`,
+		`Server`: `{"host": "localhost", "port": 8080}`,
+		`Tags`:   `["a;b", "c"]`,
+	}
+
+	err := Scan(&obj, func(field reflect.StructField, value *reflect.Value) error {
+		return ParseField(field, values[field.Name], value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eq(testPoint{1, 2}, obj.Point, t)
+	eq(`localhost`, obj.Server.Host, t)
+	eq(8080, obj.Server.Port, t)
+	eq([]string{`a;b`, `c`}, obj.Tags, t)
+
+	var syntaxErr *json.SyntaxError
+	fv := reflect.ValueOf(&obj.Tags).Elem()
+	if err := ParseWith(`[`, &fv, ParseOptions{Overwrite: true, Format: FormatJSON}); !errors.As(err, &syntaxErr) {
+		t.Errorf("expected the json error, got: %v", err)
+	}
+}
+
+func TestParsePtr(t *testing.T) {
+	var obj struct {
+		Int   *int
+		Str   *string
+		Unset *string
+		Set   *int
+		Sli   *[]int
+	}
+	set := 5
+	obj.Set = &set
+
+	values := map[string]string{
+		`Int`: `4`,
+		`Str`: `test`,
+		`Set`: `6`,
+		`Sli`: `1;2`,
+	}
+
+	err := Scan(&obj, func(field reflect.StructField, value *reflect.Value) error {
+		return Parse(values[field.Name], value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eq(4, *obj.Int, t)
+	eq(`test`, *obj.Str, t)
+	eq([]int{1, 2}, *obj.Sli, t)
+	eq(5, set, t)
+	if obj.Unset != nil {
+		t.Error(`pointer without value should stay nil`)
+	}
+
+	fv := reflect.ValueOf(&obj.Set).Elem()
+	if err := ParseHard(`7`, &fv); err != nil {
+		t.Fatal(err)
+	}
+	eq(7, set, t)
+}
+
+func TestParseAll(t *testing.T) {
+	obj := new(testObj)
+	obj.ShouldNotOverWrite = `another test`
+
+	if err := ParseAll(obj, `default`); err != nil {
+		t.Fatal(err)
+	}
+
+	eq(`test`, obj.Str, t)
+	eq(``, obj.shouldNotRead, t)
+	eq(`another test`, obj.ShouldNotOverWrite, t)
+	eq(true, obj.Bool, t)
+	eq(4.5, obj.Flt, t)
+	eq(4, obj.Int, t)
+	eq([]int{1, 2, 3}, obj.Sli, t)
+
+	if obj.File == nil {
+		t.Error(`file not parsed`)
+	}
+
+	if err := Close(obj); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseNumericUnderscores(t *testing.T) {
+	var obj struct {
+		Int  int64   `default:"1_000_000"`
+		Uint uint16  `default:"0x_ff_ff"`
+		Flt  float64 `default:"1_000.5"`
+		Str  string  `default:"1_000"`
+	}
+
+	if err := ParseAll(&obj, `default`); err != nil {
+		t.Fatal(err)
+	}
+
+	eq(1000000, obj.Int, t)
+	eq(65535, obj.Uint, t)
+	eq(1000.5, obj.Flt, t)
+	eq(`1_000`, obj.Str, t)
+
+	fv := reflect.ValueOf(&obj.Int).Elem()
+	if err := ParseHard(`1__000`, &fv); err == nil {
+		t.Error(`expected an error for underscores not allowed by the go syntax`)
+	}
+}
+
+func TestParseFieldRequired(t *testing.T) {
+	var obj struct {
+		Optional string
+		Set      string `required:"true"`
+		Port     int    `required:"true"`
+	}
+	obj.Set = `set`
+
+	err := Scan(&obj, func(field reflect.StructField, value *reflect.Value) error {
+		return ParseField(field, ``, value)
+	})
+	if !errors.Is(err, ErrRequired) {
+		t.Fatalf("expected ErrRequired, got: %v", err)
+	}
+
+	if !strings.Contains(err.Error(), `Port`) {
+		t.Errorf("error should name the field: %v", err)
+	}
+}
+
+func TestScanSliceOfStructs(t *testing.T) {
+	type server struct {
+		Host string `default:"localhost"`
+		Port int    `default:"80"`
+	}
+
+	var obj struct {
+		Servers  []server
+		Pointers []*server
+		Empty    []server
+	}
+	obj.Servers = []server{{Host: `example.com`}, {Port: 8080}}
+	obj.Pointers = []*server{nil, {Port: 443}}
+
+	structs := 0
+	err := ScanAll(&obj, func(reflect.StructField) error {
+		structs++
+		return nil
+	}, func(field reflect.StructField, value *reflect.Value) error {
+		return Parse(field.Tag.Get(`default`), value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eq(3, structs, t)
+	eq([]server{{`example.com`, 80}, {`localhost`, 8080}}, obj.Servers, t)
+	eq(server{`localhost`, 443}, *obj.Pointers[1], t)
+}
+
+func TestScanErrorPath(t *testing.T) {
+	type port struct {
+		Port int
+	}
+
+	var obj struct {
+		Database struct {
+			Port int
+		}
+		Servers []port
+	}
+	obj.Servers = []port{{}, {}}
+
+	values := map[string]string{`Port`: `abc`}
+	err := Scan(&obj, func(field reflect.StructField, value *reflect.Value) error {
+		return Parse(values[field.Name], value)
+	})
+
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) {
+		t.Fatalf("expected the wrapped strconv error, got: %v", err)
+	}
+	eq(`Database.Port: strconv.ParseInt: parsing "abc": invalid syntax`, err, t)
+
+	obj.Database.Port = 1
+	err = Scan(&obj, func(field reflect.StructField, value *reflect.Value) error {
+		return Parse(values[field.Name], value)
+	})
+	eq(`Servers[0].Port: strconv.ParseInt: parsing "abc": invalid syntax`, err, t)
+}
+
+type testNode struct {
+	Name string `default:"node"`
+	Next *testNode
+}
+
+func TestScanSelfReference(t *testing.T) {
+	last := &testNode{}
+	root := &testNode{Next: &testNode{Next: last}}
+	last.Next = root
+
+	if err := ParseAll(root, `default`); err != nil {
+		t.Fatal(err)
+	}
+
+	eq(`node`, root.Name, t)
+	eq(`node`, root.Next.Name, t)
+	eq(`node`, last.Name, t)
+}
+
+func TestParseComplex(t *testing.T) {
+	var obj struct {
+		C64  complex64  `default:"1+2i"`
+		C128 complex128 `default:"(-1.5-0.5i)"`
+	}
+
+	if err := ParseAll(&obj, `default`); err != nil {
+		t.Fatal(err)
+	}
+
+	eq(complex64(1+2i), obj.C64, t)
+	eq(-1.5-0.5i, obj.C128, t)
+
+	fv := reflect.ValueOf(&obj.C128).Elem()
+	var numErr *strconv.NumError
+	if err := ParseHard(`1+`, &fv); !errors.As(err, &numErr) {
+		t.Errorf("expected a strconv error, got: %v", err)
+	}
+}
+
+func TestScanWithStrictTags(t *testing.T) {
+	noop := func(reflect.StructField, *reflect.Value) error { return nil }
+
+	if err := ScanWith(new(testObj), ScanOptions{StrictTags: []string{`env`}}, noop); err != nil {
+		t.Errorf("unexpected error for a tag the field doesn't have: %v", err)
+	}
+
+	err := ScanWith(new(testObj), ScanOptions{StrictTags: []string{`env`, `default`}}, noop)
+	if !errors.Is(err, ErrUnexported) {
+		t.Fatalf("expected ErrUnexported, got: %v", err)
+	}
+	eq(`shouldNotRead: unexported field`, err, t)
+
+	if err := Scan(new(testObj), noop); err != nil {
+		t.Errorf("scan should be lenient by default: %v", err)
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	type octet uint8
+
+	var obj struct {
+		Raw     []byte  `default:"secret"`
+		Base64  []byte  `default:"c2VjcmV0" encoding:"base64"`
+		Hex     []byte  `default:"736563726574" encoding:"hex"`
+		Unknown []byte  `default:"secret" encoding:"rot13"`
+		Octets  []octet `default:"1;2"`
+	}
+
+	err := Scan(&obj, func(field reflect.StructField, value *reflect.Value) error {
+		if field.Name == `Unknown` {
+			if err := ParseField(field, field.Tag.Get(`default`), value); err == nil {
+				t.Error(`expected an error for an unknown encoding`)
+			}
+			return nil
+		}
+
+		return ParseField(field, field.Tag.Get(`default`), value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eq(`secret`, string(obj.Raw), t)
+	eq(`secret`, string(obj.Base64), t)
+	eq(`secret`, string(obj.Hex), t)
+	eq([]octet{1, 2}, obj.Octets, t)
+}
+
+func TestParseArray(t *testing.T) {
+	var obj struct {
+		RGB     [3]uint8  `default:"255; 128; 0"`
+		Partial [3]int    `default:"1;2"`
+		Nested  [2][2]int `default:"1,2;3"`
+	}
+
+	if err := ParseAll(&obj, `default`); err != nil {
+		t.Fatal(err)
+	}
+
+	eq([3]uint8{255, 128, 0}, obj.RGB, t)
+	eq([3]int{1, 2, 0}, obj.Partial, t)
+	eq([2][2]int{{1, 2}, {3, 0}}, obj.Nested, t)
+
+	fv := reflect.ValueOf(&obj.RGB).Elem()
+	if err := ParseHard(`1;2;3;4`, &fv); err == nil {
+		t.Error(`expected an error for too many elements`)
+	}
+	eq([3]uint8{255, 128, 0}, obj.RGB, t)
+}
+
+func TestScanValidate(t *testing.T) {
+	var obj struct {
+		Name string `default:"server"`
+		Port int    `default:"80"`
+	}
+
+	parse := func(field reflect.StructField, value *reflect.Value) error {
+		return Parse(field.Tag.Get(`default`), value)
+	}
+
+	validate := func(field reflect.StructField, value reflect.Value) error {
+		if field.Name == `Port` && (value.Int() < 1 || value.Int() > 65535) {
+			return fmt.Errorf(`port %d out of range`, value.Int())
+		}
+
+		return nil
+	}
+
+	if err := ScanValidate(&obj, parse, validate); err != nil {
+		t.Fatal(err)
+	}
+	eq(80, obj.Port, t)
+
+	obj.Port = 70000
+	if err := ScanValidate(&obj, parse, validate); err == nil {
+		t.Error(`expected a validation error`)
+	}
+}
+
+func TestParseWithExpandEnv(t *testing.T) {
+	os.Setenv(`STRCT_TEST_DIR`, `/var/$STRCT_TEST_DIR`) // nolint: errcheck
+	defer os.Unsetenv(`STRCT_TEST_DIR`)                 // nolint: errcheck
+
+	var paths []string
+	fv := reflect.ValueOf(&paths).Elem()
+	if err := ParseWith(`${STRCT_TEST_DIR}/data;$STRCT_TEST_DIR`, &fv, ParseOptions{ExpandEnv: true}); err != nil {
+		t.Fatal(err)
+	}
+	eq([]string{`/var/$STRCT_TEST_DIR/data`, `/var/$STRCT_TEST_DIR`}, paths, t)
+
+	if err := ParseHard(`$STRCT_TEST_DIR`, &fv); err != nil {
+		t.Fatal(err)
+	}
+	eq([]string{`$STRCT_TEST_DIR`}, paths, t)
+}
+
+func TestParseFieldWithExpandEnvRequired(t *testing.T) {
+	var obj struct {
+		Token string `required:"true"`
+	}
+
+	field, _ := reflect.TypeOf(obj).FieldByName(`Token`)
+	fv := reflect.ValueOf(&obj).Elem().Field(0)
+	err := ParseFieldWith(field, `${STRCT_TEST_UNSET}`, &fv, ParseOptions{ExpandEnv: true})
+	if !errors.Is(err, ErrRequired) {
+		t.Errorf("expected ErrRequired for an unset variable, got: %v", err)
+	}
+}
+
+type testLevel int
+
+func (l *testLevel) String() string {
+	return [...]string{`debug`, `info`, `error`}[*l]
+}
+
+func (l *testLevel) Set(val string) error {
+	for i, name := range [...]string{`debug`, `info`, `error`} {
+		if name == val {
+			*l = testLevel(i)
+			return nil
+		}
+	}
+
+	return fmt.Errorf(`unknown level: %s`, val)
+}
+
+func TestParseFlagValue(t *testing.T) {
+	var obj struct {
+		Level testLevel  `default:"info"`
+		Ptr   *testLevel `default:"error"`
+	}
+
+	if err := ParseAll(&obj, `default`); err != nil {
+		t.Fatal(err)
+	}
+
+	eq(`info`, obj.Level.String(), t)
+	eq(`error`, obj.Ptr.String(), t)
+
+	fv := reflect.ValueOf(&obj.Level).Elem()
+	if err := ParseHard(`1`, &fv); err == nil {
+		t.Error(`expected the error of the flag value instead of parsing an int`)
+	}
+}
+
+func TestParseZeroValues(t *testing.T) {
+	var obj struct {
+		Str   string         `default:"default"`
+		Zero  int            `default:"4"`
+		Empty []int          `default:"1;2"`
+		Map   map[string]int `default:"a=1"`
+	}
+	obj.Str = `0`
+	obj.Empty = []int{}
+	obj.Map = map[string]int{}
+
+	if err := ParseAll(&obj, `default`); err != nil {
+		t.Fatal(err)
+	}
+
+	eq(`0`, obj.Str, t)
+	eq(4, obj.Zero, t)
+	eq([]int{}, obj.Empty, t)
+	eq(map[string]int{}, obj.Map, t)
+}
+
+func TestApplyDefaults(t *testing.T) {
+	var obj struct {
+		testObj
+		Timeout time.Duration `default:"1m"`
+		Nested  struct {
+			testObj
+		}
+	}
+	obj.ShouldNotOverWrite = `another test`
+
+	if err := ApplyDefaults(&obj); err != nil {
+		t.Fatal(err)
+	}
+	defer Close(&obj) // nolint: errcheck
+
+	for _, o := range []testObj{obj.testObj, obj.Nested.testObj} {
+		eq(`test`, o.Str, t)
+		eq(``, o.shouldNotRead, t)
+		eq(true, o.Bool, t)
+		eq(4.5, o.Flt, t)
+		eq(4, o.Int, t)
+		eq([]int{1, 2, 3}, o.Sli, t)
+		if o.File == nil {
+			t.Error(`file not parsed`)
+		}
+	}
+
+	eq(`another test`, obj.ShouldNotOverWrite, t)
+	eq(`override`, obj.Nested.ShouldNotOverWrite, t)
+	eq(time.Minute, obj.Timeout, t)
+}
+
+func TestParseNetTypes(t *testing.T) {
+	var obj struct {
+		URL *url.URL `default:"https://user@example.com:8080/path?q=1"`
+		IP  net.IP   `default:"192.168.0.1"`
+		IP6 net.IP   `default:"::1"`
+	}
+
+	if err := ApplyDefaults(&obj); err != nil {
+		t.Fatal(err)
+	}
+
+	eq(`example.com:8080`, obj.URL.Host, t)
+	eq(`/path`, obj.URL.Path, t)
+	eq(`192.168.0.1`, obj.IP, t)
+	eq(net.IPv6loopback, obj.IP6, t)
+
+	fv := reflect.ValueOf(&obj.URL).Elem()
+	if err := ParseHard(`%zz`, &fv); err == nil {
+		t.Error(`expected an url parse error`)
+	}
+
+	fv = reflect.ValueOf(&obj.IP).Elem()
+	if err := ParseHard(`256.0.0.1`, &fv); err == nil {
+		t.Error(`expected an error for an invalid ip`)
+	}
+}
+
+func TestParseQuotedSliceElements(t *testing.T) {
+	tests := []struct {
+		val      string
+		expected []string
+	}{
+		{`"a;b";c`, []string{`a;b`, `c`}},
+		{` a ; " padded " ;`, []string{`a`, ` padded `, ``}},
+		{`"say ""hi""";""`, []string{`say "hi"`, ``}},
+		{`a"b;c`, []string{`a"b`, `c`}},
+	}
+
+	for _, tt := range tests {
+		var sli []string
+		fv := reflect.ValueOf(&sli).Elem()
+		if err := Parse(tt.val, &fv); err != nil {
+			t.Fatal(err)
+		}
+		eq(len(tt.expected), len(sli), t)
+		for i := range tt.expected {
+			eq(tt.expected[i], sli[i], t)
+		}
+	}
+
+	for _, val := range []string{`"a;b`, `"a"b;c`} {
+		var sli []string
+		fv := reflect.ValueOf(&sli).Elem()
+		if err := Parse(val, &fv); err == nil {
+			t.Errorf("expected an error for: %s", val)
+		}
+	}
+}
+
+func TestParseNestedDurations(t *testing.T) {
+	var obj struct {
+		Slice  []time.Duration          `default:"1s;2m;3h"`
+		Map    map[string]time.Duration `default:"read=1s;write=1m30s"`
+		Nested [][]time.Duration        `default:"1s,2s;3s"`
+		Array  [2]time.Duration         `default:"1ms;1us"`
+		Ptr    *time.Duration           `default:"5s"`
+	}
+
+	if err := ApplyDefaults(&obj); err != nil {
+		t.Fatal(err)
+	}
+
+	eq([]time.Duration{time.Second, 2 * time.Minute, 3 * time.Hour}, obj.Slice, t)
+	eq(map[string]time.Duration{`read`: time.Second, `write`: 90 * time.Second}, obj.Map, t)
+	eq([][]time.Duration{{time.Second, 2 * time.Second}, {3 * time.Second}}, obj.Nested, t)
+	eq([2]time.Duration{time.Millisecond, time.Microsecond}, obj.Array, t)
+	eq(5*time.Second, *obj.Ptr, t)
+
+	fv := reflect.ValueOf(&obj.Slice).Elem()
+	if err := ParseHard(`1s;5`, &fv); err == nil {
+		t.Error(`expected a duration error for an element without unit`)
+	}
+}
+
+func TestScanSkipTag(t *testing.T) {
+	var obj struct {
+		Parsed   string `default:"parsed"`
+		Computed string `strct:"-" default:"skipped"`
+		Nested   struct {
+			Str string `default:"skipped"`
+		} `strct:"-"`
+	}
+
+	structs := 0
+	err := ScanAll(&obj, func(reflect.StructField) error {
+		structs++
+		return nil
+	}, func(field reflect.StructField, value *reflect.Value) error {
+		return Parse(field.Tag.Get(`default`), value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eq(0, structs, t)
+	eq(`parsed`, obj.Parsed, t)
+	eq(``, obj.Computed, t)
+	eq(``, obj.Nested.Str, t)
+}
+
+func TestParseWithShouldOverwrite(t *testing.T) {
+	var obj struct {
+		Empty string
+		Slice []string
+	}
+
+	opts := ParseOptions{
+		ShouldOverwrite: func(fv reflect.Value) bool {
+			return fv.Kind() != reflect.String && fv.IsZero()
+		},
+	}
+
+	err := Scan(&obj, func(field reflect.StructField, value *reflect.Value) error {
+		return ParseWith(`value`, value, opts)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eq(``, obj.Empty, t)
+	eq([]string{`value`}, obj.Slice, t)
+}
+
+func TestScanMapOfStructs(t *testing.T) {
+	type service struct {
+		Host string `default:"localhost"`
+		Port int    `default:"80"`
+	}
+
+	var obj struct {
+		Services map[string]service
+		Pointers map[string]*service
+		Nil      map[string]service
+	}
+	obj.Services = map[string]service{`api`: {Port: 8080}, `web`: {Host: `example.com`}}
+	obj.Pointers = map[string]*service{`db`: {Port: 5432}, `nil`: nil}
+
+	if err := ApplyDefaults(&obj); err != nil {
+		t.Fatal(err)
+	}
+
+	eq(service{`localhost`, 8080}, obj.Services[`api`], t)
+	eq(service{`example.com`, 80}, obj.Services[`web`], t)
+	eq(service{`localhost`, 5432}, *obj.Pointers[`db`], t)
+
+	obj.Services[`api`] = service{Port: -1}
+	err := ScanValidate(&obj, func(reflect.StructField, *reflect.Value) error { return nil },
+		func(field reflect.StructField, value reflect.Value) error {
+			if field.Name == `Port` && value.Int() < 0 {
+				return fmt.Errorf(`negative port`)
+			}
+			return nil
+		})
+	eq(`Services[api].Port: negative port`, err, t)
+}
+
+func TestParseBool(t *testing.T) {
+	tests := map[string]bool{
+		`yes`:      true,
+		`ON`:       true,
+		`Enabled`:  true,
+		`1`:        true,
+		`True`:     true,
+		`no`:       false,
+		`Off`:      false,
+		`DISABLED`: false,
+		`f`:        false,
+	}
+
+	for val, expected := range tests {
+		var b bool
+		fv := reflect.ValueOf(&b).Elem()
+		if err := ParseHard(val, &fv); err != nil {
+			t.Fatal(err)
+		}
+		eq(expected, b, t)
+	}
+
+	var b bool
+	fv := reflect.ValueOf(&b).Elem()
+	if err := ParseHard(`maybe`, &fv); err == nil || !strings.Contains(err.Error(), `maybe`) {
+		t.Errorf("expected an error naming the value, got: %v", err)
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	var obj struct {
+		Override string `override:"OVERRIDE" env:"ENV" default:"default"`
+		Env      string `override:"UNSET" env:"ENV" default:"default"`
+		Default  string `env:"EMPTY" default:"default"`
+		Required string `env:"UNSET" required:"true"`
+	}
+
+	env := map[string]string{`OVERRIDE`: `override`, `ENV`: `env`, `EMPTY`: ``}
+	lookup := func(key string) (string, bool) {
+		if v, ok := env[key]; ok {
+			return v, true
+		}
+
+		// default tag values are literals
+		return key, key == `default`
+	}
+
+	err := Scan(&obj, func(field reflect.StructField, value *reflect.Value) error {
+		return ParseTags(field, value, lookup, `override`, `env`, `default`)
+	})
+	if !errors.Is(err, ErrRequired) {
+		t.Errorf("expected ErrRequired, got: %v", err)
+	}
+
+	eq(`override`, obj.Override, t)
+	eq(`env`, obj.Env, t)
+	eq(`default`, obj.Default, t)
+}
+
+func TestParseTimeSlice(t *testing.T) {
+	var obj struct {
+		Times []time.Time `default:"2020-01-01T00:00:00Z;2021-01-01T00:00:00Z"`
+		Dates []time.Time `default:"2020-01-01; 2021-06-15" timeformat:"2006-01-02"`
+	}
+
+	if err := ParseAll(&obj, `default`); err != nil {
+		t.Fatal(err)
+	}
+
+	eq([]time.Time{time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}, obj.Times, t)
+	eq([]time.Time{time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 6, 15, 0, 0, 0, 0, time.UTC)}, obj.Dates, t)
+
+	fv := reflect.ValueOf(&obj.Times).Elem()
+	err := ParseHard(`2020-01-01T00:00:00Z;2021-13-01T00:00:00Z`, &fv)
+	if err == nil || !strings.Contains(err.Error(), `2021-13-01T00:00:00Z`) {
+		t.Errorf("expected an error with the malformed element, got: %v", err)
+	}
+}
+
+func TestParseFieldWithoutScan(t *testing.T) {
+	var obj struct {
+		Date time.Time `timeformat:"2006-01-02"`
+		Key  []byte    `encoding:"hex"`
+	}
+
+	rv := reflect.ValueOf(&obj).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		fv := rv.Field(i)
+		if err := ParseField(rv.Type().Field(i), map[int]string{0: `2019-10-12`, 1: `ff00`}[i], &fv); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	eq(time.Date(2019, 10, 12, 0, 0, 0, 0, time.UTC), obj.Date, t)
+	eq([]byte{0xff, 0}, obj.Key, t)
+
+	field, _ := rv.Type().FieldByName(`Date`)
+	fv := rv.FieldByName(`Date`)
+	opts := ParseOptions{Overwrite: true, TimeLayout: time.RFC1123}
+	if err := ParseFieldWith(field, `2020-02-02`, &fv, opts); err != nil {
+		t.Fatal(err)
+	}
+	eq(time.Date(2020, 2, 2, 0, 0, 0, 0, time.UTC), obj.Date, t)
+}
+
+func TestParseBig(t *testing.T) {
+	var obj struct {
+		Int   *big.Int   `default:"123456789012345678901234567890"`
+		Hex   *big.Int   `default:"0xffffffffffffffffff"`
+		Float *big.Float `default:"1.5e400"`
+	}
+
+	if err := ApplyDefaults(&obj); err != nil {
+		t.Fatal(err)
+	}
+
+	eq(`123456789012345678901234567890`, obj.Int, t)
+	eq(`4722366482869645213695`, obj.Hex, t)
+	eq(`1.5e+400`, obj.Float.Text('g', 10), t)
+
+	// the scanner dereferences non nil pointers to structs
+	fv := reflect.ValueOf(obj.Int).Elem()
+	if err := ParseHard(`42`, &fv); err != nil {
+		t.Fatal(err)
+	}
+	eq(42, obj.Int, t)
+
+	fv = reflect.ValueOf(&obj.Int).Elem()
+	if err := ParseHard(`12ab`, &fv); err == nil || !strings.Contains(err.Error(), `12ab`) {
+		t.Errorf("expected a descriptive error, got: %v", err)
+	}
+
+	fv = reflect.ValueOf(&obj.Float).Elem()
+	if err := ParseHard(`1.2.3`, &fv); err == nil {
+		t.Error(`expected an error for an invalid float`)
+	}
+}
+
+func TestScanDepth(t *testing.T) {
+	type level2 struct {
+		Str string
+	}
+
+	type level1 struct {
+		Str    string
+		Level2 level2
+		Slice  []level2
+	}
+
+	var obj struct {
+		Str    string
+		Level1 level1
+	}
+	obj.Level1.Slice = []level2{{}}
+
+	for depth, expected := range map[int][]string{
+		0: {`Str`, `Level1`},
+		1: {`Str`, `Level1.Str`, `Level1.Level2`, `Level1.Slice`, `Level1`},
+		2: {`Str`, `Level1.Str`, `Level1.Level2.Str`, `Level1.Level2`, `Level1.Slice[0].Str`, `Level1.Slice`, `Level1`},
+	} {
+		var paths []string
+		s := &scanner{
+			opts:     ScanOptions{MaxDepth: depth + 1},
+			onStruct: func(context.Context, string, reflect.StructField) error { return nil },
+			onProperty: func(_ context.Context, path string, _ reflect.StructField, _ *reflect.Value) error {
+				paths = append(paths, path)
+				return nil
+			},
+		}
+
+		if err := s.scanObj(context.Background(), &obj); err != nil {
+			t.Fatal(err)
+		}
+		eq(expected, paths, t)
+	}
+
+	names := 0
+	err := ScanDepth(&obj, 0, func(reflect.StructField, *reflect.Value) error {
+		names++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(2, names, t)
+}
+
+func TestParseFileMode(t *testing.T) {
+	for _, val := range []string{`644`, `0644`, `0o644`, `0o6_44`, `6_4_4`} {
+		var mode os.FileMode
+		fv := reflect.ValueOf(&mode).Elem()
+		if err := Parse(val, &fv); err != nil {
+			t.Fatal(err)
+		}
+		eq(os.FileMode(0644), mode, t)
+	}
+
+	var mode os.FileMode
+	fv := reflect.ValueOf(&mode).Elem()
+	for _, val := range []string{`0x1ff`, `64__4`, `644_`, `844`} {
+		if err := Parse(val, &fv); err == nil {
+			t.Errorf("expected an error for %s", val)
+		}
+	}
+}
+
+func TestParseTimePtr(t *testing.T) {
+	var obj struct {
+		Start *time.Time `default:"2019-10-12T07:20:50Z"`
+		Date  *time.Time `default:"2019-10-12" timeformat:"2006-01-02"`
+		Unset *time.Time
+	}
+
+	if err := ParseAll(&obj, `default`); err != nil {
+		t.Fatal(err)
+	}
+
+	eq(time.Date(2019, 10, 12, 7, 20, 50, 0, time.UTC), *obj.Start, t)
+	eq(time.Date(2019, 10, 12, 0, 0, 0, 0, time.UTC), *obj.Date, t)
+	if obj.Unset != nil {
+		t.Error(`time without value should stay nil`)
+	}
+}
+
+func TestScanWithOnStruct(t *testing.T) {
+	var obj struct {
+		Database struct {
+			Primary struct {
+				Host string `env:"HOST"`
+			} `env:"PRIMARY_"`
+		} `env:"DB_"`
+		Caches []struct {
+			Host string `env:"HOST"`
+		} `env:"CACHE_"`
+	}
+	obj.Caches = make([]struct {
+		Host string `env:"HOST"`
+	}, 1)
+
+	prefixes := map[string]string{}
+	opts := ScanOptions{
+		PrefixTag: `env`,
+		OnStruct: func(path string, field reflect.StructField) error {
+			prefixes[path] = field.Tag.Get(`env`)
+			return nil
+		},
+	}
+
+	if err := ScanWith(&obj, opts, func(reflect.StructField, *reflect.Value) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	eq(map[string]string{
+		`Database`:         `DB_`,
+		`Database.Primary`: `DB_PRIMARY_`,
+		`Caches[0]`:        `CACHE_`,
+	}, prefixes, t)
+}
+
+func TestParseISODuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		`PT1H30M`:   90 * time.Minute,
+		`P1DT2H`:    26 * time.Hour,
+		`P2W`:       14 * 24 * time.Hour,
+		`PT1.5S`:    1500 * time.Millisecond,
+		`PT0,5S`:    500 * time.Millisecond,
+		`-PT10M`:    -10 * time.Minute,
+		`+PT10M`:    10 * time.Minute,
+		`P1D`:       24 * time.Hour,
+		`PT36H`:     36 * time.Hour,
+		`PT1M0.25S`: time.Minute + 250*time.Millisecond,
+	}
+
+	for val, expected := range tests {
+		var d time.Duration
+		fv := reflect.ValueOf(&d).Elem()
+		if err := ParseWith(val, &fv, ParseOptions{Format: FormatISO8601}); err != nil {
+			t.Fatalf("%s: %v", val, err)
+		}
+		eq(expected, d, t)
+	}
+
+	for _, val := range []string{`P`, `PT`, `1H`, `P1Y`, `P1M`, `PT1D`, `P1H`, `PTT1H`, `PT1`, `PTxS`, `1h`, `PT1S1H`, `PT1H1H`, `P1DT1M1H`, `P1000000D`, `-PT3000000H`, `--PT1S`, `+-PT1S`, `PT1.5H30M`, `P1.5DT1H`} {
+		var d time.Duration
+		fv := reflect.ValueOf(&d).Elem()
+		if err := ParseWith(val, &fv, ParseOptions{Format: FormatISO8601}); err == nil {
+			t.Errorf("expected an error for: %s", val)
+		}
+	}
+
+	var obj struct {
+		ISO time.Duration `default:"PT1H" format:"iso8601"`
+		Go  time.Duration `default:"-1h"`
+	}
+
+	if err := ApplyDefaults(&obj); err != nil {
+		t.Fatal(err)
+	}
+	eq(time.Hour, obj.ISO, t)
+	eq(-time.Hour, obj.Go, t)
+}
+
+func TestScanReadOnly(t *testing.T) {
+	type service struct {
+		Host string
+		Port int
+	}
+
+	type config struct {
+		Name     string
+		Primary  service
+		Services map[string]service
+		secret   string
+	}
+
+	obj := config{
+		Name:     `app`,
+		Primary:  service{`localhost`, 5432},
+		Services: map[string]service{`api`: {`example.com`, 8080}},
+		secret:   `hidden`,
+	}
+
+	props := map[string]interface{}{}
+	err := ScanReadOnly(obj, func(field reflect.StructField, value *reflect.Value) error {
+		if value.CanSet() {
+			return fmt.Errorf(`%s is settable`, field.Name)
+		}
+
+		props[field.Name] = value.Interface()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eq(`app`, props[`Name`], t)
+	eq(`example.com`, props[`Host`], t)
+	eq(8080, props[`Port`], t)
+	if _, found := props[`secret`]; found {
+		t.Error(`expected the unexported field to be skipped`)
+	}
+
+	var fields []string
+	if err := ScanReadOnly(&obj, func(field reflect.StructField, value *reflect.Value) error {
+		fields = append(fields, field.Name)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	eq([]string{`Name`, `Host`, `Port`, `Primary`, `Host`, `Port`, `Services`}, fields, t)
+	eq(ErrNotStruct, ScanReadOnly(`value`, func(reflect.StructField, *reflect.Value) error { return nil }), t)
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]uint64{
+		`512`:     512,
+		`10B`:     10,
+		`10MB`:    10e6,
+		`2KiB`:    2048,
+		`1.5GiB`:  3 << 29,
+		`3 gb`:    3e9,
+		`1TiB`:    1 << 40,
+		`1_000KB`: 1e6,
+		`1_0.5KB`: 10500,
+	}
+
+	for val, expected := range tests {
+		var n uint64
+		fv := reflect.ValueOf(&n).Elem()
+		if err := ParseWith(val, &fv, ParseOptions{Format: FormatBytes}); err != nil {
+			t.Fatalf("%s: %v", val, err)
+		}
+		eq(expected, n, t)
+	}
+
+	for _, val := range []string{`10XB`, `MB`, `1.2.3KB`, `-1KB`, `20000000TiB`, `_1KB`, `1__0KB`, `1_KB`, `1_.5KB`} {
+		var n uint64
+		fv := reflect.ValueOf(&n).Elem()
+		if err := ParseWith(val, &fv, ParseOptions{Format: FormatBytes}); err == nil {
+			t.Errorf("expected an error for: %s", val)
+		}
+	}
+
+	var obj struct {
+		Limit  int    `default:"4KiB" format:"bytes"`
+		Buffer uint16 `default:"1MB" format:"bytes"`
+	}
+
+	err := ApplyDefaults(&obj)
+	if err == nil || !strings.Contains(err.Error(), `Buffer`) {
+		t.Errorf("expected an out of range error for Buffer, got: %v", err)
+	}
+	eq(4096, obj.Limit, t)
+}
+
+func TestScanAllErrors(t *testing.T) {
+	var obj struct {
+		Port    int     `default:"http"`
+		Name    string  `default:"app"`
+		Ratio   float64 `default:"half"`
+		Primary struct {
+			Enabled bool `default:"maybe"`
+		}
+	}
+
+	err := ScanAllErrors(&obj, func(reflect.StructField) error { return nil }, func(field reflect.StructField, value *reflect.Value) error {
+		return ParseHard(field.Tag.Get(`default`), value)
+	})
+
+	var numErr *strconv.NumError
+	if !errors.Is(err, strconv.ErrSyntax) || !errors.As(err, &numErr) || errors.Is(err, ErrRequired) {
+		t.Errorf("expected errors.Is and errors.As to match the field errors, got: %v", err)
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("expected a combined error, got: %v", err)
+	}
+
+	errs := joined.Unwrap()
+	eq(3, len(errs), t)
+	for i, prefix := range []string{`Port: `, `Ratio: `, `Primary.Enabled: `} {
+		if i < len(errs) && !strings.HasPrefix(errs[i].Error(), prefix) {
+			t.Errorf("expected error %d to start with %q, got: %v", i, prefix, errs[i])
+		}
+	}
+	eq(`app`, obj.Name, t)
+
+	err = ScanAllErrors(&obj, func(reflect.StructField) error { return nil }, func(reflect.StructField, *reflect.Value) error { return nil })
+	if err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+}
+
+func TestScanSkipChanAndFunc(t *testing.T) {
+	var obj struct {
+		Name     string `default:"app"`
+		Done     chan struct{}
+		OnChange func(string)
+		notify   chan string `env:"NOTIFY"`
+	}
+
+	var fields []string
+	err := ScanWith(&obj, ScanOptions{StrictTags: []string{`env`}}, func(field reflect.StructField, value *reflect.Value) error {
+		fields = append(fields, field.Name)
+		return ParseHard(field.Tag.Get(`default`), value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eq([]string{`Name`}, fields, t)
+	eq(`app`, obj.Name, t)
+}
+
+func TestParseOpenDatabase(t *testing.T) {
+	pool, err := sql.Open(`strcttest`, `shared`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close() // nolint: errcheck
+
+	var obj struct {
+		Users  *sql.DB `default:"strcttest/shared" db:"ping"`
+		Orders *sql.DB `default:"strcttest/shared"`
+		Other  *sql.DB `default:"strcttest/other"`
+	}
+
+	opts := ParseOptions{
+		OpenDatabase: func(val string) (*sql.DB, error) {
+			if val != `strcttest/shared` {
+				return nil, nil
+			}
+
+			return pool, nil
+		},
+	}
+
+	err = Scan(&obj, func(field reflect.StructField, value *reflect.Value) error {
+		return ParseFieldWith(field, field.Tag.Get(`default`), value, opts)
+	})
+	if err == nil || !strings.Contains(err.Error(), `Other`) {
+		t.Errorf("expected an error for the missing database, got: %v", err)
+	}
+
+	if obj.Users != pool || obj.Orders != pool {
+		t.Error(`expected the fields to share the pool`)
+	}
+
+	if err := Close(&obj); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pool.Ping(); err != nil {
+		t.Errorf("shared pool should not be closed: %v", err)
+	}
+}
+
+func TestParseInterfaceInference(t *testing.T) {
+	tests := map[string]interface{}{
+		`true`:                true,
+		`FALSE`:               false,
+		`1`:                   1,
+		`0`:                   0,
+		`-42`:                 -42,
+		`1.0`:                 1.0,
+		`1e3`:                 1000.0,
+		`-0.5`:                -0.5,
+		`yes`:                 `yes`,
+		`0x1f`:                `0x1f`,
+		`NaN`:                 `NaN`,
+		`inf`:                 `inf`,
+		`1.2.3`:               `1.2.3`,
+		`  padded `:           `  padded `,
+		`9999999999999999999`: 1e19,
+		`1_000`:               1000,
+		`1_000.5`:             1000.5,
+		`1__000`:              `1__000`,
+		`_1`:                  `_1`,
+	}
+
+	for val, expected := range tests {
+		var v interface{}
+		fv := reflect.ValueOf(&v).Elem()
+		if err := ParseHard(val, &fv); err != nil {
+			t.Fatalf("%s: %v", val, err)
+		}
+
+		if v != expected {
+			t.Errorf("%q: expected %T(%v), got %T(%v)", val, expected, expected, v, v)
+		}
+	}
+
+	var obj struct {
+		Values []interface{} `default:"on;2;2.5"`
+	}
+
+	if err := ApplyDefaults(&obj); err != nil {
+		t.Fatal(err)
+	}
+	eq([]interface{}{`on`, 2, 2.5}, obj.Values, t)
+}
+
+func TestParseSourceFile(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `strct`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	token := filepath.Join(dir, `token`)
+	if err := ioutil.WriteFile(token, []byte("  s3cr3t\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var obj struct {
+		Token   string `source:"file"`
+		Secret  string `source:"file"`
+		Missing string `source:"file"`
+	}
+	obj.Token = `unchanged`
+
+	values := map[string]string{`Secret`: token, `Missing`: filepath.Join(dir, `missing`)}
+	err = Scan(&obj, func(field reflect.StructField, value *reflect.Value) error {
+		return ParseFieldWith(field, values[field.Name], value, ParseOptions{Overwrite: true})
+	})
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the read error of the missing file, got: %v", err)
+	}
+
+	eq(`unchanged`, obj.Token, t)
+	eq(`  s3cr3t`, obj.Secret, t)
+}
+
+func TestParseQuotedString(t *testing.T) {
+	var obj struct {
+		Padded   string   `default:"\"  padded  \"" quoted:"true"`
+		Escaped  string   `default:" \"say \"\"hi\"\"\" " quoted:"true"`
+		Plain    string   `default:"  plain " quoted:"true"`
+		Verbatim string   `default:"\"kept\""`
+		Elements []string `default:"\" a \";\"\"\"b\"\"\"" quoted:"true"`
+		Invalid  string   `default:"\"a\"b\"" quoted:"true"`
+	}
+
+	err := ApplyDefaults(&obj)
+	if err == nil || !strings.Contains(err.Error(), `Invalid`) {
+		t.Errorf("expected an error for the unescaped quote, got: %v", err)
+	}
+
+	eq(`  padded  `, obj.Padded, t)
+	eq(`say "hi"`, obj.Escaped, t)
+	eq(`  plain `, obj.Plain, t)
+	eq(`"kept"`, obj.Verbatim, t)
+	eq([]string{` a `, `"b"`}, obj.Elements, t)
+}
+
+func TestParseTCPAddr(t *testing.T) {
+	var obj struct {
+		Listen *net.TCPAddr `default:":8080"`
+		Peer   *net.TCPAddr `default:"127.0.0.1:9000"`
+		IPv6   net.TCPAddr  `default:"[fe80::1%eth0]:443"`
+	}
+
+	if err := ApplyDefaults(&obj); err != nil {
+		t.Fatal(err)
+	}
+
+	eq(`:8080`, obj.Listen, t)
+	eq(`127.0.0.1:9000`, obj.Peer, t)
+	eq(net.ParseIP(`fe80::1`), obj.IPv6.IP, t)
+	eq(443, obj.IPv6.Port, t)
+	eq(`eth0`, obj.IPv6.Zone, t)
+
+	var resolved struct {
+		Addr *net.TCPAddr `resolve:"true"`
+	}
+
+	err := Scan(&resolved, func(field reflect.StructField, value *reflect.Value) error {
+		return ParseField(field, `localhost:80`, value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resolved.Addr == nil || !resolved.Addr.IP.IsLoopback() || resolved.Addr.Port != 80 {
+		t.Errorf("expected localhost to resolve to a loopback address, got: %v", resolved.Addr)
+	}
+
+	for val, msg := range map[string]string{
+		`127.0.0.1`:       `missing port`,
+		`127.0.0.1:http`:  `invalid port "http"`,
+		`127.0.0.1:70000`: `invalid port "70000"`,
+		`localhost:80`:    `isn't an ip address`,
+	} {
+		var addr *net.TCPAddr
+		fv := reflect.ValueOf(&addr).Elem()
+		if err := ParseHard(val, &fv); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: expected an error containing %q, got: %v", val, msg, err)
+		}
+	}
+}
+
+func TestParseFieldSeparator(t *testing.T) {
+	var obj struct {
+		Path    []string       `default:"/usr/bin:/bin" sep:":"`
+		Hosts   []string       `default:"a.com,b.com" sep:","`
+		Ports   []int          `default:"80;443"`
+		Weights map[string]int `default:"a=1|b=2" sep:"|"`
+	}
+
+	if err := ApplyDefaults(&obj); err != nil {
+		t.Fatal(err)
+	}
+
+	eq([]string{`/usr/bin`, `/bin`}, obj.Path, t)
+	eq([]string{`a.com`, `b.com`}, obj.Hosts, t)
+	eq([]int{80, 443}, obj.Ports, t)
+	eq(map[string]int{`a`: 1, `b`: 2}, obj.Weights, t)
+}
+
+func TestScanFilter(t *testing.T) {
+	var obj struct {
+		Name     string `override:"true"`
+		Port     int
+		Database struct {
+			Host string `override:"true"`
+			User string
+		}
+	}
+
+	overrides := map[string]string{`Name`: `app`, `Port`: `80`, `Host`: `db`, `User`: `admin`}
+	err := ScanFilter(&obj, func(field reflect.StructField) bool {
+		return field.Tag.Get(`override`) == `true`
+	}, func(field reflect.StructField, value *reflect.Value) error {
+		return ParseHard(overrides[field.Name], value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eq(`app`, obj.Name, t)
+	eq(0, obj.Port, t)
+	eq(`db`, obj.Database.Host, t)
+	eq(``, obj.Database.User, t)
+}
+
+func TestScanNoPtrErrors(t *testing.T) {
+	onProperty := func(reflect.StructField, *reflect.Value) error { return nil }
+
+	var nilObj *testObj
+	str := `value`
+	tests := []struct {
+		obj interface{}
+		err error
+	}{
+		{testObj{}, ErrNotPtr},
+		{nil, ErrNotPtr},
+		{nilObj, ErrNilPtr},
+		{&str, ErrNotStruct},
+	}
+
+	for _, tt := range tests {
+		err := Scan(tt.obj, onProperty)
+		if !errors.Is(err, tt.err) || !errors.Is(err, ErrNoPtr) {
+			t.Errorf("%T: expected %v matching ErrNoPtr, got: %v", tt.obj, tt.err, err)
+		}
+	}
+
+	if errors.Is(ErrNilPtr, ErrNotPtr) || errors.Is(ErrNotStruct, ErrNilPtr) {
+		t.Error(`expected the errors to be distinct`)
+	}
+}
+
+func TestParseFieldOneOf(t *testing.T) {
+	type level string
+
+	var obj struct {
+		Level  level  `oneof:"debug info warn error"`
+		Format string `oneof:"json text"`
+		Empty  string `oneof:"json text"`
+	}
+
+	values := map[string]string{`Level`: `info`, `Format`: `JSON`}
+	err := Scan(&obj, func(field reflect.StructField, value *reflect.Value) error {
+		return ParseField(field, values[field.Name], value)
+	})
+	if !errors.Is(err, ErrNotAllowed) {
+		t.Fatalf("expected ErrNotAllowed, got: %v", err)
+	}
+	eq(`Format: value not allowed: Format is "JSON" but must be one of json, text`, err, t)
+	eq(level(`info`), obj.Level, t)
+	eq(``, obj.Empty, t)
+}
+
+func TestParseSQLNull(t *testing.T) {
+	var obj struct {
+		Name    sql.NullString  `default:"app"`
+		Port    sql.NullInt64   `default:"5432"`
+		Ratio   sql.NullFloat64 `default:"0.5"`
+		Enabled sql.NullBool    `default:"yes"`
+		Since   sql.NullTime    `default:"2020-01-01T00:00:00Z"`
+		Unset   sql.NullString
+	}
+
+	if err := ApplyDefaults(&obj); err != nil {
+		t.Fatal(err)
+	}
+
+	eq(sql.NullString{String: `app`, Valid: true}, obj.Name, t)
+	eq(sql.NullInt64{Int64: 5432, Valid: true}, obj.Port, t)
+	eq(sql.NullFloat64{Float64: 0.5, Valid: true}, obj.Ratio, t)
+	eq(sql.NullBool{Bool: true, Valid: true}, obj.Enabled, t)
+	eq(sql.NullTime{Time: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true}, obj.Since, t)
+	eq(sql.NullString{}, obj.Unset, t)
+
+	var n sql.NullInt64
+	fv := reflect.ValueOf(&n).Elem()
+	if err := ParseHard(`many`, &fv); err == nil {
+		t.Error(`expected an error for an invalid integer`)
+	}
+	eq(false, n.Valid, t)
+}
+
+func TestParseStrictNumbers(t *testing.T) {
+	strict := ParseOptions{Overwrite: true, StrictNumbers: true}
+
+	var f32 float32
+	fv := reflect.ValueOf(&f32).Elem()
+	for _, val := range []string{`0.1`, `1.5`, `16777216`, `1e10`, `NaN`} {
+		if err := ParseWith(val, &fv, strict); err != nil {
+			t.Errorf("%s: %v", val, err)
+		}
+	}
+
+	for _, val := range []string{`0.123456789`, `16777217`, `1e40`} {
+		if err := ParseWith(val, &fv, strict); err == nil {
+			t.Errorf("expected an error for %s as float32", val)
+		}
+	}
+
+	if err := ParseHard(`0.123456789`, &fv); err != nil {
+		t.Errorf("expected the default to be lenient: %v", err)
+	}
+
+	var f64 float64
+	fv = reflect.ValueOf(&f64).Elem()
+	if err := ParseWith(`9007199254740993`, &fv, strict); err == nil {
+		t.Error(`expected an error for an integer beyond the float64 precision`)
+	}
+
+	if err := ParseWith(`0.1`, &fv, strict); err != nil {
+		t.Error(err)
+	}
+
+	var i8 int8
+	fv = reflect.ValueOf(&i8).Elem()
+	if err := ParseWith(`300`, &fv, strict); err == nil {
+		t.Error(`expected an overflow error`)
+	}
+
+	var size uint64
+	fv = reflect.ValueOf(&size).Elem()
+	strict.Format = FormatBytes
+	if err := ParseWith(`1.5KiB`, &fv, strict); err != nil {
+		t.Error(err)
+	}
+	eq(1536, size, t)
+
+	if err := ParseWith(`1.5B`, &fv, strict); err == nil {
+		t.Error(`expected an error for a fraction of a byte`)
+	}
+}
+
+func TestScanSkipsUnexportedEmbedded(t *testing.T) {
+	file, err := os.Open(`./base.go`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close() // nolint: errcheck
+
+	obj := struct{ File *os.File }{file}
+	var structs []string
+	err = ScanWith(&obj, ScanOptions{OnStruct: func(path string, _ reflect.StructField) error {
+		structs = append(structs, path)
+		return nil
+	}}, func(reflect.StructField, *reflect.Value) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eq([]string{`File`}, structs, t)
+}
+
+func TestParseHardSetURL(t *testing.T) {
+	var obj struct {
+		URL   *url.URL
+		Value url.URL
+	}
+	obj.URL, _ = url.Parse(`http://old`)
+
+	err := Scan(&obj, func(field reflect.StructField, value *reflect.Value) error {
+		if field.Name != `URL` && field.Name != `Value` {
+			return nil
+		}
+
+		return ParseHard(`http://new/path`, value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eq(`http://new/path`, obj.URL, t)
+	eq(`http://new/path`, &obj.Value, t)
+}
+
 func eq(expected, actual interface{}, t *testing.T) {
 	if fmt.Sprint(expected) != fmt.Sprint(actual) {
 		t.Errorf("Unexpected value:\nexpected: %v\nactual: %v\n", expected, actual)
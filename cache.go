@@ -0,0 +1,79 @@
+// Copyright 2019 Job Stoit. All rights reserved.
+
+package strct
+
+import (
+	"reflect"
+	"sync"
+)
+
+// cachedField is a field of a struct type which is scanned
+type cachedField struct {
+	index int
+	field reflect.StructField
+
+	// embedded reports if the field embeds a struct with exported fields which are scanned even if the
+	// embedded struct itself is unexported
+	embedded bool
+}
+
+var (
+	fieldsMu    sync.RWMutex
+	fieldsCache = map[reflect.Type][]cachedField{}
+)
+
+// structFields returns the fields of the struct type which are scanned, the fields are reflected once per type
+// since the layout of a type never changes
+func structFields(t reflect.Type) []cachedField {
+	fieldsMu.RLock()
+	fields, ok := fieldsCache[t]
+	fieldsMu.RUnlock()
+	if ok {
+		return fields
+	}
+
+	fields = reflectFields(t)
+	fieldsMu.Lock()
+	fieldsCache[t] = fields
+	fieldsMu.Unlock()
+	return fields
+}
+
+// reflectFields reflects the fields of the struct type which are scanned
+func reflectFields(t reflect.Type) []cachedField {
+	fields := make([]cachedField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get(`strct`) == `-` {
+			continue
+		}
+
+		// channels and funcs can't be parsed from a string so they're intentionally unsupported
+		if k := field.Type.Kind(); k == reflect.Chan || k == reflect.Func {
+			continue
+		}
+
+		fields = append(fields, cachedField{i, field, field.Anonymous && hasExported(field.Type)})
+	}
+
+	return fields
+}
+
+// hasExported reports if the struct or pointer to a struct has exported fields
+func hasExported(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == `` {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,83 @@
+// Copyright 2019 Job Stoit. All rights reserved.
+
+package strct
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type benchConfig struct {
+	Name     string        `env:"NAME" default:"app"`
+	Port     int           `env:"PORT" default:"8080"`
+	Debug    bool          `env:"DEBUG"`
+	Timeout  time.Duration `env:"TIMEOUT" default:"5s"`
+	Hosts    []string      `env:"HOSTS"`
+	Internal string        `strct:"-"`
+	Database struct {
+		Host     string `env:"DB_HOST" default:"localhost"`
+		Port     int    `env:"DB_PORT" default:"5432"`
+		User     string `env:"DB_USER"`
+		Password string `env:"DB_PASSWORD"`
+	}
+	Cache struct {
+		Size int           `env:"CACHE_SIZE" default:"100"`
+		TTL  time.Duration `env:"CACHE_TTL" default:"1m"`
+	}
+	notify chan struct{}
+}
+
+func BenchmarkScan(b *testing.B) {
+	var obj benchConfig
+	onProperty := func(reflect.StructField, *reflect.Value) error { return nil }
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := Scan(&obj, onProperty); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStructFields(b *testing.B) {
+	t := reflect.TypeOf(benchConfig{})
+
+	b.Run(`reflect`, func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			reflectFields(t)
+		}
+	})
+
+	b.Run(`cached`, func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			structFields(t)
+		}
+	})
+}
+
+func TestStructFields(t *testing.T) {
+	fields := structFields(reflect.TypeOf(benchConfig{}))
+
+	names := make([]string, len(fields))
+	for i, cf := range fields {
+		names[i] = cf.field.Name
+	}
+	eq([]string{`Name`, `Port`, `Debug`, `Timeout`, `Hosts`, `Database`, `Cache`}, names, t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var obj benchConfig
+			if err := ApplyDefaults(&obj); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
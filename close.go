@@ -0,0 +1,134 @@
+// Copyright 2019 Job Stoit. All rights reserved.
+
+package strct
+
+import (
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+var (
+	openedMu sync.Mutex
+	opened   = map[visit]struct{}{}
+)
+
+// track registers a file or database opened by the parser so it can be closed by Close. Only the address is kept so
+// the closer can still be garbage collected, its finalizer removes the address before the memory can be reused
+func track(c io.Closer) {
+	openedMu.Lock()
+	opened[closerKey(c)] = struct{}{}
+	openedMu.Unlock()
+
+	runtime.SetFinalizer(c, func(c io.Closer) { untrack(c) })
+}
+
+// untrack removes the closer from the opened closers and reports if it was opened by the parser
+func untrack(c io.Closer) bool {
+	key := closerKey(c)
+
+	openedMu.Lock()
+	defer openedMu.Unlock()
+
+	if _, ok := opened[key]; !ok {
+		return false
+	}
+
+	delete(opened, key)
+	return true
+}
+
+// closerKey returns the key of a closer, the parser only opens pointers to files and databases
+func closerKey(c io.Closer) visit {
+	v := reflect.ValueOf(c)
+	return visit{v.Pointer(), v.Type()}
+}
+
+// Close closes the files and databases opened by the parser which are reachable from the given object struct
+// including the elements of slices, arrays and maps. Closers which weren't opened by the parser like the databases
+// returned by OpenDatabase, fields with the `strct:"-"` tag and nil values are skipped. Closing is idempotent since
+// closers are only closed once, the first error of a closer is returned after closing the others
+func Close(obj interface{}) error {
+	rv := reflect.ValueOf(obj)
+	switch {
+	case rv.Kind() != reflect.Ptr:
+		return ErrNotPtr
+	case rv.IsNil():
+		return ErrNilPtr
+	case rv.Elem().Kind() != reflect.Struct:
+		return ErrNotStruct
+	}
+
+	c := &closer{visited: map[visit]bool{}}
+	c.close(rv)
+	return c.err
+}
+
+// closer closes the closers of an object struct where visited holds the pointers which were already followed
+type closer struct {
+	visited map[visit]bool
+	err     error
+}
+
+func (c *closer) close(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() || !v.CanInterface() {
+			return
+		}
+
+		c.close(v.Elem())
+
+	case reflect.Ptr:
+		if v.IsNil() || !v.CanInterface() {
+			return
+		}
+
+		key := visit{v.Pointer(), v.Type()}
+		if c.visited[key] {
+			return
+		}
+		c.visited[key] = true
+
+		if cl, ok := v.Interface().(io.Closer); ok {
+			c.closeOne(cl)
+			return
+		}
+		c.close(v.Elem())
+
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := t.Field(i)
+			if field.Tag.Get(`strct`) == `-` || field.PkgPath != `` && !field.Anonymous {
+				continue
+			}
+
+			c.close(v.Field(i))
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			c.close(v.Index(i))
+		}
+
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			c.close(iter.Value())
+		}
+	}
+}
+
+func (c *closer) closeOne(cl io.Closer) {
+	if !untrack(cl) {
+		return
+	}
+
+	if err := cl.Close(); err != nil && !errors.Is(err, os.ErrClosed) && c.err == nil {
+		c.err = err
+	}
+}
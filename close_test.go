@@ -0,0 +1,83 @@
+// Copyright 2019 Job Stoit. All rights reserved.
+
+package strct
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestClose(t *testing.T) {
+	external, err := os.Open(`./base.go`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer external.Close() // nolint: errcheck
+
+	var obj struct {
+		File     *os.File  `default:"./base.go"`
+		Reader   io.Reader `default:"./close.go"`
+		Nil      io.ReadCloser
+		External *os.File
+		Nested   struct {
+			Writer io.WriteCloser `default:"./base_test.go"`
+		}
+		Files   []*os.File           `default:"./base.go;./close.go"`
+		Readers map[string]io.Reader `default:"file=./close_test.go"`
+		Skipped *os.File             `strct:"-"`
+	}
+	obj.External = external
+
+	err = Scan(&obj, func(field reflect.StructField, value *reflect.Value) error {
+		return Parse(field.Tag.Get(`default`), value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj.Skipped, err = os.Open(`./close_test.go`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obj.Skipped.Close() // nolint: errcheck
+
+	if err := Close(&obj); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := obj.File.Stat(); err == nil {
+		t.Error(`file should be closed`)
+	}
+
+	if _, err := obj.Reader.Read(make([]byte, 1)); err == nil {
+		t.Error(`reader should be closed`)
+	}
+
+	if _, err := obj.Nested.Writer.(*os.File).Stat(); err == nil {
+		t.Error(`nested writer should be closed`)
+	}
+
+	if _, err := external.Stat(); err != nil {
+		t.Error(`files which weren't opened by the parser should not be closed`)
+	}
+
+	for _, f := range obj.Files {
+		if _, err := f.Stat(); err == nil {
+			t.Error(`files of a slice should be closed`)
+		}
+	}
+
+	if _, err := obj.Readers[`file`].(*os.File).Stat(); err == nil {
+		t.Error(`files of a map should be closed`)
+	}
+
+	if _, err := obj.Skipped.Stat(); err != nil {
+		t.Error(`skipped files should not be closed`)
+	}
+
+	if err := Close(&obj); err != nil {
+		t.Errorf("closing twice should not fail: %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2019 Job Stoit. All rights reserved.
+
+package strct
+
+import (
+	"database/sql"
+	"math/big"
+	"os"
+	"reflect"
+)
+
+// ParseCopy deep copies the given object struct, scans the copy with onProperty and returns it leaving the given
+// object struct untouched. A pointer returns a pointer to the copy and a struct value returns the copied value.
+// Files and databases aren't duplicated so they're shared between the object struct and its copy
+func ParseCopy(obj interface{}, onProperty func(reflect.StructField, *reflect.Value) error) (interface{}, error) {
+	rv := reflect.ValueOf(obj)
+	ptr := rv.Kind() == reflect.Ptr
+	if ptr && rv.IsNil() {
+		return nil, ErrNilPtr
+	}
+
+	src := reflect.Indirect(rv)
+	if src.Kind() != reflect.Struct {
+		return nil, ErrNotStruct
+	}
+
+	dst := reflect.New(src.Type())
+	c := copier{copied: map[visit]reflect.Value{}}
+	if ptr {
+		// references back to the object struct point to the copy
+		c.copied[visit{rv.Pointer(), rv.Type()}] = dst
+	}
+	c.copy(dst.Elem(), src)
+
+	if err := Scan(dst.Interface(), onProperty); err != nil {
+		return nil, err
+	}
+
+	if ptr {
+		return dst.Interface(), nil
+	}
+
+	return dst.Elem().Interface(), nil
+}
+
+// copier deep copies values where pointers that were already copied are reused to keep cycles and shared pointers intact
+type copier struct {
+	copied map[visit]reflect.Value
+}
+
+// copy deep copies src onto the settable dst of the same type
+func (c copier) copy(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() {
+			dst.Set(src)
+			return
+		}
+
+		switch x := src.Interface().(type) {
+		case *os.File, *sql.DB:
+			dst.Set(src)
+			return
+		case *big.Int:
+			dst.Set(reflect.ValueOf(new(big.Int).Set(x)))
+			return
+		case *big.Float:
+			dst.Set(reflect.ValueOf(new(big.Float).Copy(x)))
+			return
+		}
+
+		v := visit{src.Pointer(), src.Type()}
+		if p, ok := c.copied[v]; ok {
+			dst.Set(p)
+			return
+		}
+
+		p := reflect.New(src.Type().Elem())
+		c.copied[v] = p
+		c.copy(p.Elem(), src.Elem())
+		dst.Set(p)
+
+	case reflect.Interface:
+		if src.IsNil() {
+			dst.Set(src)
+			return
+		}
+
+		v := reflect.New(src.Elem().Type()).Elem()
+		c.copy(v, src.Elem())
+		dst.Set(v)
+
+	case reflect.Struct:
+		dst.Set(src)
+		c.copyFields(dst, src)
+
+	case reflect.Slice:
+		if src.IsNil() {
+			dst.Set(src)
+			return
+		}
+
+		s := reflect.MakeSlice(src.Type(), src.Len(), src.Cap())
+		for i := 0; i < src.Len(); i++ {
+			c.copy(s.Index(i), src.Index(i))
+		}
+		dst.Set(s)
+
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			c.copy(dst.Index(i), src.Index(i))
+		}
+
+	case reflect.Map:
+		if src.IsNil() {
+			dst.Set(src)
+			return
+		}
+
+		m := reflect.MakeMapWithSize(src.Type(), src.Len())
+		for _, key := range src.MapKeys() {
+			v := reflect.New(src.Type().Elem()).Elem()
+			c.copy(v, src.MapIndex(key))
+			m.SetMapIndex(key, v)
+		}
+		dst.Set(m)
+
+	default:
+		dst.Set(src)
+	}
+}
+
+// copyFields deep copies the exported fields of a struct, unexported fields can't be set so they're copied shallow
+// along with the struct except for the exported fields of embedded structs
+func (c copier) copyFields(dst, src reflect.Value) {
+	for i := 0; i < dst.NumField(); i++ {
+		f := dst.Field(i)
+		switch {
+		case f.CanSet():
+			c.copy(f, src.Field(i))
+		case dst.Type().Field(i).Anonymous && f.Kind() == reflect.Struct:
+			c.copyFields(f, src.Field(i))
+		}
+	}
+}
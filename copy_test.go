@@ -0,0 +1,82 @@
+// Copyright 2019 Job Stoit. All rights reserved.
+
+package strct
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseCopy(t *testing.T) {
+	type server struct {
+		Host string `default:"localhost"`
+		Tags []string
+	}
+
+	type config struct {
+		Name    string `default:"app"`
+		Primary *server
+		Servers []server
+		Limits  map[string]int
+		Self    *config
+		server
+	}
+
+	base := &config{
+		Name:    `base`,
+		Primary: &server{Tags: []string{`primary`}},
+		Servers: []server{{Host: `api`}, {}},
+		Limits:  map[string]int{`conns`: 10},
+		server:  server{Tags: []string{`embedded`}},
+	}
+	base.Self = base
+
+	onProperty := func(field reflect.StructField, value *reflect.Value) error {
+		if field.Name == `Tags` {
+			return ParseHard(`changed`, value)
+		}
+
+		if field.Name == `Limits` {
+			value.SetMapIndex(reflect.ValueOf(`conns`), reflect.ValueOf(20))
+			return nil
+		}
+
+		return ParseWith(field.Tag.Get(`default`), value, ParseOptions{Overwrite: true})
+	}
+
+	v, err := ParseCopy(base, onProperty)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cp := v.(*config)
+	eq(`app`, cp.Name, t)
+	eq(`localhost`, cp.Primary.Host, t)
+	eq([]string{`changed`}, cp.Primary.Tags, t)
+	eq(`localhost`, cp.Servers[0].Host, t)
+	eq(20, cp.Limits[`conns`], t)
+	eq([]string{`changed`}, cp.Tags, t)
+	if cp.Self != cp {
+		t.Error(`expected the copied self reference to point to the copy`)
+	}
+
+	eq(`base`, base.Name, t)
+	eq(``, base.Primary.Host, t)
+	eq([]string{`primary`}, base.Primary.Tags, t)
+	eq(`api`, base.Servers[0].Host, t)
+	eq(``, base.Servers[1].Host, t)
+	eq(10, base.Limits[`conns`], t)
+	eq([]string{`embedded`}, base.Tags, t)
+
+	v, err = ParseCopy(*base, onProperty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(`app`, v.(config).Name, t)
+	eq(`base`, base.Name, t)
+
+	if _, err := ParseCopy(`value`, onProperty); !errors.Is(err, ErrNotStruct) {
+		t.Errorf("expected ErrNotStruct, got: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 Job Stoit. All rights reserved.
+
+package strct
+
+import (
+	"context"
+	"reflect"
+)
+
+// FieldInfo describes a property of a scanned object struct
+type FieldInfo struct {
+	// Path is the dotted path of the property within the object struct
+	Path string
+	Name string
+	Tag  reflect.StructTag
+	Type reflect.Type
+
+	// Value is the current value of the property
+	Value interface{}
+
+	// Set reports if the property already holds a value and wouldn't be set by Parse
+	Set bool
+}
+
+// CollectFields scans the given object struct and describes each property without setting it
+func CollectFields(obj interface{}) ([]FieldInfo, error) {
+	var fields []FieldInfo
+	s := &scanner{
+		readOnly: true,
+		onStruct: func(context.Context, string, reflect.StructField) error { return nil },
+		onProperty: func(_ context.Context, path string, field reflect.StructField, value *reflect.Value) error {
+			fields = append(fields, FieldInfo{
+				Path:  path,
+				Name:  field.Name,
+				Tag:   field.Tag,
+				Type:  value.Type(),
+				Value: value.Interface(),
+				Set:   !isUnset(value),
+			})
+			return nil
+		},
+	}
+
+	if err := s.scanObj(context.Background(), obj); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// Field is a scanned property of an object struct
+type Field struct {
+	reflect.StructField
+
+	// Path is the dotted path of the property within the object struct
+	Path string
+
+	// Value is the settable value of the property
+	Value *reflect.Value
+}
+
+// ScanFields scans the given object struct and returns its properties in the order Scan visits them
+func ScanFields(obj interface{}) ([]Field, error) {
+	var fields []Field
+	s := &scanner{
+		onStruct: func(context.Context, string, reflect.StructField) error { return nil },
+		onProperty: func(_ context.Context, path string, field reflect.StructField, value *reflect.Value) error {
+			v := *value
+			fields = append(fields, Field{StructField: field, Path: path, Value: &v})
+			return nil
+		},
+	}
+
+	if err := s.scanObj(context.Background(), obj); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2019 Job Stoit. All rights reserved.
+
+package strct
+
+import (
+	"database/sql"
+	"errors"
+	"net"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCollectFields(t *testing.T) {
+	var obj struct {
+		Name     string `env:"NAME"`
+		Database struct {
+			Port int `env:"PORT" default:"5432"`
+		}
+	}
+	obj.Name = `test`
+
+	fields, err := CollectFields(&obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eq(3, len(fields), t)
+	eq(`Name`, fields[0].Path, t)
+	eq(`test`, fields[0].Value, t)
+	eq(true, fields[0].Set, t)
+
+	eq(`Database.Port`, fields[1].Path, t)
+	eq(`Port`, fields[1].Name, t)
+	eq(`5432`, fields[1].Tag.Get(`default`), t)
+	eq(reflect.TypeOf(0), fields[1].Type, t)
+	eq(false, fields[1].Set, t)
+
+	eq(`Database`, fields[2].Path, t)
+	eq(0, obj.Database.Port, t)
+}
+
+func TestCollectFieldsValueTypes(t *testing.T) {
+	var obj struct {
+		Name    sql.NullString
+		Created time.Time
+		Link    *url.URL
+		Addr    net.TCPAddr
+		Backups []sql.NullString
+	}
+	obj.Link = &url.URL{Scheme: `https`, Host: `example.com`}
+
+	fields, err := CollectFields(&obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := make([]string, len(fields))
+	for i, f := range fields {
+		paths[i] = f.Path
+	}
+	eq([]string{`Name`, `Created`, `Link`, `Addr`, `Backups`}, paths, t)
+}
+
+func TestScanFields(t *testing.T) {
+	var obj struct {
+		Host     string `default:"localhost"`
+		Database struct {
+			Port int `default:"5432"`
+		}
+	}
+
+	fields, err := ScanFields(&obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eq(3, len(fields), t)
+	for _, field := range fields {
+		if err := Parse(field.Tag.Get(`default`), field.Value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	eq(`Host`, fields[0].Name, t)
+	eq(`Database.Port`, fields[1].Path, t)
+	eq(`localhost`, obj.Host, t)
+	eq(5432, obj.Database.Port, t)
+
+	if _, err := ScanFields(obj); !errors.Is(err, ErrNotPtr) {
+		t.Errorf("expected ErrNotPtr, got: %v", err)
+	}
+}
+
+func TestCollectFieldsConcurrentMaps(t *testing.T) {
+	type service struct {
+		Host string `env:"HOST"`
+	}
+
+	obj := struct {
+		Services map[string]service `env:"SERVICES_"`
+	}{Services: map[string]service{`api`: {`localhost`}}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := CollectFields(&obj); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			if _, err := Marshal(&obj, `env`); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	fields, err := CollectFields(&obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(`Services[api].Host`, fields[0].Path, t)
+	eq(`localhost`, fields[0].Value, t)
+}
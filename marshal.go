@@ -0,0 +1,277 @@
+// Copyright 2019 Job Stoit. All rights reserved.
+
+package strct
+
+import (
+	"context"
+	"database/sql"
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Marshal scans the given object struct and maps the value of the given tag of each property to the property's
+// value formatted the way the parser parses it. Tags of nested structs are prefixed like ScanPrefixed does and
+// properties that can't be represented as string, like an opened *sql.DB, are left out. Since each key holds a single
+// value an error is returned if properties share a key, like the properties of the structs of a slice
+func Marshal(obj interface{}, tag string) (map[string]string, error) {
+	m := map[string]string{}
+	s := &scanner{
+		readOnly: true,
+		opts:     ScanOptions{PrefixTag: tag},
+		onStruct: func(context.Context, string, reflect.StructField) error { return nil },
+		onProperty: func(_ context.Context, _ string, field reflect.StructField, value *reflect.Value) error {
+			key, ok := field.Tag.Lookup(tag)
+			if !ok || key == `` {
+				return nil
+			}
+
+			val, ok, err := format(*value, fieldOptions(field, ParseOptions{}))
+			if err != nil || !ok {
+				return err
+			}
+
+			if _, ok := m[key]; ok {
+				return fmt.Errorf(`duplicate key %s`, key)
+			}
+
+			m[key] = val
+			return nil
+		},
+	}
+
+	if err := s.scanObj(context.Background(), obj); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// format formats the value as string so it can be parsed using the same options,
+// it reports false if the value can't be represented as string
+func format(v reflect.Value, opts ParseOptions) (string, bool, error) { // nolint: gocyclo
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	if !v.IsValid() || v.Kind() == reflect.Ptr && v.IsNil() {
+		return ``, true, nil
+	}
+
+	if opts.Format == FormatJSON {
+		b, err := json.Marshal(v.Interface())
+		return string(b), true, err
+	}
+
+	if nullTypes[v.Type()] {
+		if !v.FieldByName(`Valid`).Bool() {
+			return ``, true, nil
+		}
+
+		return format(v.Field(0), opts)
+	}
+
+	if s, ok, err := formatPtr(v, opts); ok || err != nil {
+		return s, true, err
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == reflect.TypeOf(time.Duration(0)) {
+			if opts.Format == FormatISO8601 {
+				return formatISODuration(time.Duration(v.Int())), true, nil
+			}
+			return time.Duration(v.Int()).String(), true, nil
+		}
+		return strconv.FormatInt(v.Int(), 10), true, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Type() == fileModeType {
+			return fmt.Sprintf(`%#o`, v.Uint()), true, nil
+		}
+		return strconv.FormatUint(v.Uint(), 10), true, nil
+
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), true, nil
+
+	case reflect.Complex64, reflect.Complex128:
+		return strconv.FormatComplex(v.Complex(), 'g', -1, v.Type().Bits()), true, nil
+
+	case reflect.String:
+		return v.String(), true, nil
+
+	case reflect.Slice:
+		if v.Type().Elem() == byteType {
+			s, err := encodeBytes(v.Bytes(), opts.Encoding)
+			return s, true, err
+		}
+		return formatElements(v, opts)
+
+	case reflect.Array:
+		return formatElements(v, opts)
+
+	case reflect.Map:
+		return formatMap(v, opts)
+
+	case reflect.Ptr:
+		return format(v.Elem(), opts)
+
+	default:
+		return ``, false, nil
+	}
+}
+
+// formatPtr formats the types which are formatted through their pointer
+func formatPtr(v reflect.Value, opts ParseOptions) (string, bool, error) {
+	ptr := v
+	if v.Kind() != reflect.Ptr {
+		if !v.CanAddr() {
+			ptr = reflect.New(v.Type())
+			ptr.Elem().Set(v)
+		} else {
+			ptr = v.Addr()
+		}
+	}
+
+	switch x := ptr.Interface().(type) {
+	case *time.Time:
+		return x.Format(opts.timeLayout()), true, nil
+	case *url.URL:
+		return x.String(), true, nil
+	case *net.TCPAddr:
+		return x.String(), true, nil
+	case *os.File:
+		return x.Name(), true, nil
+	case *sql.DB:
+		return ``, false, nil
+	case encoding.TextMarshaler:
+		b, err := x.MarshalText()
+		return string(b), true, err
+	case flag.Value:
+		return x.String(), true, nil
+	default:
+		return ``, false, nil
+	}
+}
+
+func formatElements(v reflect.Value, opts ParseOptions) (string, bool, error) {
+	delim, err := opts.sliceDelimiter()
+	if err != nil {
+		return ``, false, err
+	}
+
+	parts := make([]string, v.Len())
+	for i := range parts {
+		s, ok, err := format(v.Index(i), opts.nested())
+		if err != nil || !ok {
+			return ``, ok, err
+		}
+
+		if v.Type().Elem().Kind() == reflect.String {
+			s = quoteElement(s, delim)
+		}
+		parts[i] = s
+	}
+
+	return strings.Join(parts, delim), true, nil
+}
+
+func formatMap(v reflect.Value, opts ParseOptions) (string, bool, error) {
+	delim, err := opts.sliceDelimiter()
+	if err != nil {
+		return ``, false, err
+	}
+
+	entries := make([]string, 0, v.Len())
+	for _, key := range v.MapKeys() {
+		k, ok, err := format(key, opts.nested())
+		if err != nil || !ok {
+			return ``, ok, err
+		}
+
+		e, ok, err := format(v.MapIndex(key), opts.nested())
+		if err != nil || !ok {
+			return ``, ok, err
+		}
+
+		// map entries aren't quoted by the parser so entries which wouldn't be parsed back the same are rejected
+		if strings.Contains(k, `=`) || strings.Contains(k, delim) || strings.TrimSpace(k) != k {
+			return ``, false, fmt.Errorf(`map key %q can't be formatted with delimiter %q`, k, delim)
+		}
+
+		if strings.Contains(e, delim) || strings.TrimSpace(e) != e {
+			return ``, false, fmt.Errorf(`map value %q of key %q can't be formatted with delimiter %q`, e, k, delim)
+		}
+
+		entries = append(entries, k+`=`+e)
+	}
+	sort.Strings(entries)
+
+	return strings.Join(entries, delim), true, nil
+}
+
+// quoteElement quotes a string element if it would otherwise not be parsed as the same string
+func quoteElement(s, delim string) string {
+	if !strings.Contains(s, delim) && !strings.HasPrefix(s, `"`) && strings.TrimSpace(s) == s {
+		return s
+	}
+
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func encodeBytes(b []byte, encoding string) (string, error) {
+	switch encoding {
+	case ``:
+		return string(b), nil
+	case EncodingBase64:
+		return base64.StdEncoding.EncodeToString(b), nil
+	case EncodingHex:
+		return hex.EncodeToString(b), nil
+	default:
+		return ``, fmt.Errorf(`unknown encoding: %s`, encoding)
+	}
+}
+
+// formatISODuration formats the duration as ISO 8601 duration like PT1H30M
+func formatISODuration(d time.Duration) string {
+	if d == 0 {
+		return `PT0S`
+	}
+
+	var b strings.Builder
+	if d < 0 {
+		b.WriteString(`-`)
+		d = -d
+	}
+	b.WriteString(`PT`)
+
+	if h := d / time.Hour; h > 0 {
+		fmt.Fprintf(&b, `%dH`, h)
+		d -= h * time.Hour
+	}
+
+	if m := d / time.Minute; m > 0 {
+		fmt.Fprintf(&b, `%dM`, m)
+		d -= m * time.Minute
+	}
+
+	if d > 0 {
+		b.WriteString(strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + `S`)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,170 @@
+// Copyright 2019 Job Stoit. All rights reserved.
+
+package strct
+
+import (
+	"database/sql"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Name     string         `env:"NAME"`
+	Tags     []string       `env:"TAGS"`
+	Limits   map[string]int `env:"LIMITS"`
+	Timeout  time.Duration  `env:"TIMEOUT"`
+	Started  time.Time      `env:"STARTED" timeformat:"2006-01-02"`
+	Mode     os.FileMode    `env:"MODE"`
+	Key      []byte         `env:"KEY" encoding:"hex"`
+	IP       net.IP         `env:"IP"`
+	URL      *url.URL       `env:"URL"`
+	Port     *int           `env:"PORT"`
+	DB       *sql.DB        `env:"DB"`
+	Untagged string
+	Database struct {
+		Host string `env:"HOST"`
+	} `env:"DB_"`
+}
+
+func TestMarshal(t *testing.T) {
+	obj := testConfig{
+		Name:    `test`,
+		Tags:    []string{`a;b`, `c`},
+		Limits:  map[string]int{`b`: 2, `a`: 1},
+		Timeout: 90 * time.Second,
+		Started: time.Date(2019, 10, 12, 0, 0, 0, 0, time.UTC),
+		Mode:    0644,
+		Key:     []byte{0xff, 0},
+		IP:      net.IPv4(127, 0, 0, 1),
+	}
+	obj.URL, _ = url.Parse(`https://example.com/path`)
+	obj.Database.Host = `localhost`
+
+	m, err := Marshal(&obj, `env`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eq(map[string]string{
+		`NAME`:    `test`,
+		`TAGS`:    `"a;b";c`,
+		`LIMITS`:  `a=1;b=2`,
+		`TIMEOUT`: `1m30s`,
+		`STARTED`: `2019-10-12`,
+		`MODE`:    `0644`,
+		`KEY`:     `ff00`,
+		`IP`:      `127.0.0.1`,
+		`URL`:     `https://example.com/path`,
+		`PORT`:    ``,
+		`DB`:      ``,
+		`DB_HOST`: `localhost`,
+	}, m, t)
+
+	// round trip
+	var parsed testConfig
+	err = ScanPrefixed(&parsed, `env`, func(field reflect.StructField, value *reflect.Value) error {
+		return ParseField(field, m[field.Tag.Get(`env`)], value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(obj, parsed) {
+		t.Errorf("Unexpected value after round trip:\nexpected: %+v\nactual: %+v\n", obj, parsed)
+	}
+}
+
+func TestMarshalISODuration(t *testing.T) {
+	var obj struct {
+		Timeout  time.Duration `env:"TIMEOUT" format:"iso8601"`
+		Negative time.Duration `env:"NEGATIVE" format:"iso8601"`
+		Zero     time.Duration `env:"ZERO" format:"iso8601"`
+	}
+	obj.Timeout = 26*time.Hour + 30*time.Minute + 1500*time.Millisecond
+	obj.Negative = -time.Minute
+
+	m, err := Marshal(&obj, `env`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eq(map[string]string{`TIMEOUT`: `PT26H30M1.5S`, `NEGATIVE`: `-PT1M`, `ZERO`: `PT0S`}, m, t)
+}
+
+func TestMarshalSQLNull(t *testing.T) {
+	obj := struct {
+		Name  sql.NullString `env:"NAME"`
+		Port  sql.NullInt64  `env:"PORT"`
+		Since sql.NullTime   `env:"SINCE"`
+	}{
+		Name:  sql.NullString{String: `app`, Valid: true},
+		Port:  sql.NullInt64{Int64: 5432},
+		Since: sql.NullTime{Time: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+	}
+
+	m, err := Marshal(&obj, `env`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eq(map[string]string{`NAME`: `app`, `PORT`: ``, `SINCE`: `2020-01-01T00:00:00Z`}, m, t)
+}
+
+func TestMarshalKeys(t *testing.T) {
+	type inner struct {
+		Tags string `env:"TAGS"`
+	}
+
+	var obj struct {
+		Outer struct {
+			Untagged struct {
+				Inner inner `env:"INNER_"`
+			}
+		} `env:"OUTER_"`
+		List []inner `env:"LIST_"`
+	}
+	obj.Outer.Untagged.Inner.Tags = `a`
+
+	m, err := Marshal(&obj, `env`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(map[string]string{`OUTER_INNER_TAGS`: `a`, `LIST_`: ``}, m, t)
+
+	obj.List = []inner{{`b`}, {`c`}}
+	if _, err := Marshal(&obj, `env`); err == nil || !strings.Contains(err.Error(), `List[1].Tags: duplicate key LIST_TAGS`) {
+		t.Errorf("expected a duplicate key error, got: %v", err)
+	}
+}
+
+func TestMarshalMapRoundTrip(t *testing.T) {
+	type config struct {
+		Labels map[string]string `env:"LABELS"`
+	}
+
+	obj := config{Labels: map[string]string{`a`: `x=y`, `b`: `z`}}
+	m, err := Marshal(&obj, `env`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parsed config
+	err = Scan(&parsed, func(field reflect.StructField, value *reflect.Value) error {
+		return ParseField(field, m[field.Tag.Get(`env`)], value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq(obj.Labels, parsed.Labels, t)
+
+	for _, labels := range []map[string]string{{`a`: `x;y`}, {`a;b`: `x`}, {`a=b`: `x`}, {`a`: ` x`}} {
+		if _, err := Marshal(&config{Labels: labels}, `env`); err == nil {
+			t.Errorf("expected an error for entries which can't be parsed back: %v", labels)
+		}
+	}
+}
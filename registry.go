@@ -0,0 +1,97 @@
+// Copyright 2019 Job Stoit. All rights reserved.
+
+package strct
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	parsersMu sync.RWMutex
+	parsers   = map[reflect.Type]func(string) (interface{}, error){}
+)
+
+// RegisterParser registers a parser for the given type which is used by the parser before any of the built in types,
+// registering a parser for an already registered type overwrites the previous parser.
+// It's safe to register parsers while other goroutines are parsing
+func RegisterParser(t reflect.Type, fn func(string) (interface{}, error)) {
+	parsersMu.Lock()
+	parsers[t] = fn
+	parsersMu.Unlock()
+}
+
+func parseRegistered(val string, fv *reflect.Value) (bool, error) {
+	parsersMu.RLock()
+	fn, ok := parsers[fv.Type()]
+	parsersMu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+
+	v, err := fn(val)
+	if err != nil {
+		return true, err
+	}
+
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || !rv.Type().AssignableTo(fv.Type()) {
+		return true, fmt.Errorf(`registered parser for %s returned %T`, fv.Type(), v)
+	}
+
+	fv.Set(rv)
+	return true, nil
+}
+
+var (
+	implsMu sync.RWMutex
+	impls   = map[reflect.Type]map[string]reflect.Type{}
+)
+
+// RegisterInterfaceImpl registers the concrete type which is allocated for a field of the given interface type
+// when the parsed value is the given key. The key may be followed by a colon and a value which is parsed
+// onto the allocated type like key:value. The concrete type or a pointer to it has to implement the interface,
+// registering an already registered key overwrites the previous concrete type
+func RegisterInterfaceImpl(ifaceType reflect.Type, key string, concrete reflect.Type) {
+	implsMu.Lock()
+	if impls[ifaceType] == nil {
+		impls[ifaceType] = map[string]reflect.Type{}
+	}
+	impls[ifaceType][key] = concrete
+	implsMu.Unlock()
+}
+
+func parseInterfaceImpl(val string, fv *reflect.Value, opts ParseOptions) (bool, error) {
+	key, rest := val, ``
+	if i := strings.Index(val, `:`); i >= 0 {
+		key, rest = val[:i], val[i+1:]
+	}
+
+	implsMu.RLock()
+	concrete, ok := impls[fv.Type()][key]
+	implsMu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+
+	ptr := reflect.New(concrete)
+	if rest != `` {
+		elem := ptr.Elem()
+		if err := ParseWith(rest, &elem, opts.nested()); err != nil {
+			return true, fmt.Errorf(`%s: %w`, key, err)
+		}
+	}
+
+	switch {
+	case concrete.Implements(fv.Type()):
+		fv.Set(ptr.Elem())
+	case ptr.Type().Implements(fv.Type()):
+		fv.Set(ptr)
+	default:
+		return true, fmt.Errorf(`%s registered for %s doesn't implement %s`, concrete, key, fv.Type())
+	}
+
+	return true, nil
+}
@@ -0,0 +1,150 @@
+// Copyright 2019 Job Stoit. All rights reserved.
+
+package strct
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type testID [2]string
+
+func TestRegisterParser(t *testing.T) {
+	RegisterParser(reflect.TypeOf(testID{}), func(val string) (interface{}, error) {
+		return nil, fmt.Errorf(`should be overwritten`)
+	})
+	RegisterParser(reflect.TypeOf(testID{}), func(val string) (interface{}, error) {
+		parts := strings.SplitN(val, `-`, 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf(`invalid id: %s`, val)
+		}
+
+		return testID{parts[0], parts[1]}, nil
+	})
+
+	var id testID
+	fv := reflect.ValueOf(&id).Elem()
+	if err := ParseHard(`ab-cd`, &fv); err != nil {
+		t.Fatal(err)
+	}
+	eq(testID{`ab`, `cd`}, id, t)
+
+	if err := ParseHard(`abcd`, &fv); err == nil {
+		t.Error(`expected the error of the registered parser`)
+	}
+}
+
+func TestRegisterParserWrongType(t *testing.T) {
+	type wrong string
+	RegisterParser(reflect.TypeOf(wrong(``)), func(val string) (interface{}, error) {
+		return val, nil
+	})
+
+	var w wrong
+	fv := reflect.ValueOf(&w).Elem()
+	if err := ParseHard(`test`, &fv); err == nil {
+		t.Error(`expected an error for a parser returning the wrong type`)
+	}
+}
+
+func TestRegisterParserConcurrently(t *testing.T) {
+	type celsius float64
+	type fahrenheit float64
+
+	SetDefaultDriver(`strcttest`)
+	defer SetDefaultDriver(``)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			RegisterParser(reflect.TypeOf(celsius(0)), func(val string) (interface{}, error) {
+				v, err := strconv.ParseFloat(strings.TrimSuffix(val, `C`), 64)
+				return celsius(v), err
+			})
+			SetDefaultDriver(`strcttest`)
+		}()
+
+		go func() {
+			defer wg.Done()
+			var obj struct {
+				Celsius    celsius    `default:"21.5C"`
+				Fahrenheit fahrenheit `default:"70.7"`
+				DB         *sql.DB    `default:"reachable"`
+			}
+
+			errs <- ApplyDefaults(&obj)
+			Close(&obj) // nolint: errcheck
+			RegisterParser(reflect.TypeOf(fahrenheit(0)), func(val string) (interface{}, error) {
+				v, err := strconv.ParseFloat(val, 64)
+				return fahrenheit(v), err
+			})
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		var numErr *strconv.NumError
+		if err != nil && !errors.As(err, &numErr) {
+			t.Error(err)
+		}
+	}
+}
+
+type testStorage interface {
+	Location() string
+}
+
+type testDisk string
+
+func (d testDisk) Location() string { return string(d) }
+
+type testBucket struct {
+	Name   string `json:"name"`
+	Region string `json:"region"`
+}
+
+func (b *testBucket) Location() string { return b.Region + `/` + b.Name }
+
+func TestRegisterInterfaceImpl(t *testing.T) {
+	storageType := reflect.TypeOf((*testStorage)(nil)).Elem()
+	RegisterInterfaceImpl(storageType, `disk`, reflect.TypeOf(testDisk(``)))
+	RegisterInterfaceImpl(storageType, `s3`, reflect.TypeOf(testBucket{}))
+	RegisterInterfaceImpl(storageType, `wrong`, reflect.TypeOf(0))
+
+	// an alias gives the embedded field an exported name
+	type Storage = testStorage
+	var obj struct {
+		Storage `default:"disk:/var/data"`
+		Backup  testStorage `default:"s3:{\"name\":\"backup\",\"region\":\"eu\"}" format:"json"`
+		Empty   testStorage `default:"disk"`
+	}
+
+	if err := ApplyDefaults(&obj); err != nil {
+		t.Fatal(err)
+	}
+
+	eq(testDisk(`/var/data`), obj.Storage, t)
+	eq(&testBucket{`backup`, `eu`}, obj.Backup, t)
+	eq(testDisk(``), obj.Empty, t)
+
+	var s testStorage
+	fv := reflect.ValueOf(&s).Elem()
+	if err := ParseHard(`ftp:host`, &fv); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("expected ErrUnsupportedType for an unregistered key, got: %v", err)
+	}
+
+	if err := ParseHard(`wrong`, &fv); err == nil {
+		t.Error(`expected an error for an implementation not implementing the interface`)
+	}
+}